@@ -3,47 +3,71 @@ package main
 import (
 	"context"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webserver"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/usecase"
 	otelpkg "github.com/felipeksw/goexpert-fullcycle-cloud-run/pkg/otel"
 )
 
-func main() {
+// version is set at build time with -ldflags "-X main.version=..."
+var version string
 
-	slog.SetLogLoggerLevel(slog.LevelDebug)
+func main() {
 
-	chSo := make(chan os.Signal, 1)
-	signal.Notify(chSo, os.Interrupt, syscall.SIGINT)
+	var handler slog.Handler = otelpkg.NewTraceHandler(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	)
+	if otelpkg.LogsEnabled() {
+		// the debug records carry upstream bodies, only Info and above are exported
+		handler = otelpkg.NewBridgeHandler("service-a", handler, slog.LevelInfo)
+	}
+	slog.SetDefault(slog.New(handler))
 
-	ctx, shutdownSo := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT)
+	ctx, shutdownSo := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	defer shutdownSo()
 
-	ShutdownProvider, err := otelpkg.InitProvider(ctx, "service-a", "otel-collector:4317")
+	ShutdownProvider, err := otelpkg.InitProvider(ctx,
+		otelpkg.WithServiceName("service-a"),
+		otelpkg.WithServiceVersion(version),
+		otelpkg.WithBaggageSpanAttributes(webserver.BaggageTenant, webserver.BaggageClientApp),
+	)
 	if err != nil {
 		slog.Error("[InitProvider]", "error", err.Error())
 		os.Exit(5)
 	}
-	defer func() {
-		if err := ShutdownProvider(ctx); err != nil {
-			slog.Error("[ShutdownProvider]", "error", err.Error())
-			os.Exit(5)
-		}
-	}()
-
-	ws := webserver.NewWebServer(os.Getenv("SERVICE_A_PORT"))
-	ws.AddHandler("POST /zipcode/", webserver.GetZipcodeHandler)
-	errWs := ws.Start()
+
+	ws := webserver.NewWebServer(os.Getenv("SERVICE_A_PORT"),
+		webserver.WithMaxConcurrentRequests(100),
+		webserver.WithBaggageFromHeaders(),
+	)
+	ws.Use(
+		webserver.RequestID,
+		webserver.AccessLog,
+		webserver.Recoverer,
+		webserver.CORS(webserver.CORSConfig{AllowedOrigins: []string{"*"}}),
+	)
+	ws.AddHandler("POST /zipcode/", webserver.GetZipcodeHandler, webserver.WithRouteMaxBodyBytes(1<<10))
+	ws.AddReadinessCheck("service-b", func(ctx context.Context) error {
+		return usecase.CheckServiceB(ctx, http.DefaultClient)
+	})
+	errWs := ws.Start(ctx)
 	if errWs != nil {
 		slog.Error("could not start the webserver:" + errWs.Error())
 	}
 
-	select {
-	case <-chSo:
-		slog.Info("Shutting down gracefully wbc2, CTRL+C pressed...")
-	case <-ctx.Done():
-		slog.Info("Shutting down gracefully wbc2, interrupet system...")
+	slog.Info("Shutting down gracefully, flushing telemetry...")
+
+	// ctx is already done here, the spans of the drained requests are flushed with a fresh deadline
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := ShutdownProvider(flushCtx); err != nil {
+		slog.Error("[ShutdownProvider]", "error", err.Error())
+		os.Exit(5)
 	}
 }
@@ -0,0 +1,85 @@
+// Package domainerr holds the errors the handlers turn into responses. The
+// sentinels are matched with errors.Is, wrapped causes stay reachable with
+// errors.As, e.g. the webclient.StatusError of a failed upstream call.
+package domainerr
+
+import (
+	"errors"
+	"net/http"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+var (
+	ErrInvalidZipcode      = errors.New("invalid zipcode")
+	ErrZipcodeNotFound     = errors.New("zip code not found")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	ErrUpstreamRateLimited = errors.New("upstream rate limited")
+	ErrInvalidTemperature  = errors.New("temperature is outside the earth range")
+)
+
+// Error classifies Err under one of the sentinels. StatusCode is the status
+// answered by the upstream, 0 when the call did not get a response.
+type Error struct {
+	Kind       error
+	StatusCode int
+	Err        error
+}
+
+func Wrap(kind error, statusCode int, err error) error {
+	return &Error{Kind: kind, StatusCode: statusCode, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// HTTPStatus is the status code a handler answers for err.
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidZipcode):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrZipcodeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUpstreamUnavailable), errors.Is(err, ErrUpstreamRateLimited):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrInvalidTemperature):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// Message is the contractual text of the error body for err. Unclassified
+// errors are not exposed to the client.
+func Message(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidZipcode):
+		return "invalid zipcode"
+	case errors.Is(err, ErrZipcodeNotFound):
+		return "can not find zipcode"
+	case errors.Is(err, ErrUpstreamUnavailable), errors.Is(err, ErrUpstreamRateLimited):
+		return "upstream unavailable"
+	case errors.Is(err, ErrInvalidTemperature):
+		return ErrInvalidTemperature.Error()
+	}
+	return "internal server error"
+}
+
+// SpanStatus leaves the span unset for the errors caused by the client, as
+// the semantic conventions ask for server spans, and sets Error otherwise.
+func SpanStatus(err error) (codes.Code, string) {
+	if HTTPStatus(err) < http.StatusInternalServerError {
+		return codes.Unset, ""
+	}
+	return codes.Error, err.Error()
+}
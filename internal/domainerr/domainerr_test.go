@@ -0,0 +1,55 @@
+package domainerr_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/domainerr"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestHTTPStatus(t *testing.T) {
+
+	cause := errors.New("api.weatherapi.com: Too Many Requests")
+
+	type Lote struct {
+		err    error
+		status int
+		msg    string
+		span   codes.Code
+	}
+
+	table := []Lote{
+		{domainerr.ErrInvalidZipcode, http.StatusUnprocessableEntity, "invalid zipcode", codes.Unset},
+		{fmt.Errorf("failed to read zipcode: %w", domainerr.ErrInvalidZipcode), http.StatusUnprocessableEntity, "invalid zipcode", codes.Unset},
+		{domainerr.Wrap(domainerr.ErrZipcodeNotFound, http.StatusNotFound, nil), http.StatusNotFound, "can not find zipcode", codes.Unset},
+		{domainerr.Wrap(domainerr.ErrUpstreamRateLimited, http.StatusTooManyRequests, cause), http.StatusServiceUnavailable, "upstream unavailable", codes.Error},
+		{errors.Join(errors.New("WEATHER_API_KEY is not set"), domainerr.Wrap(domainerr.ErrUpstreamUnavailable, 0, cause)), http.StatusServiceUnavailable, "upstream unavailable", codes.Error},
+		{domainerr.ErrInvalidTemperature, http.StatusBadGateway, "temperature is outside the earth range", codes.Error},
+		{errors.New("unexpected"), http.StatusInternalServerError, "internal server error", codes.Error},
+	}
+
+	for _, item := range table {
+		assert.Equal(t, item.status, domainerr.HTTPStatus(item.err), item.err.Error())
+		assert.Equal(t, item.msg, domainerr.Message(item.err), item.err.Error())
+		code, _ := domainerr.SpanStatus(item.err)
+		assert.Equal(t, item.span, code, item.err.Error())
+	}
+}
+
+func TestErrorWrapsCause(t *testing.T) {
+
+	cause := errors.New("api.weatherapi.com: Too Many Requests")
+	err := fmt.Errorf("weather: %w", domainerr.Wrap(domainerr.ErrUpstreamRateLimited, http.StatusTooManyRequests, cause))
+
+	assert.True(t, errors.Is(err, domainerr.ErrUpstreamRateLimited))
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, "weather: upstream rate limited: api.weatherapi.com: Too Many Requests", err.Error())
+
+	var derr *domainerr.Error
+	assert.True(t, errors.As(err, &derr))
+	assert.Equal(t, http.StatusTooManyRequests, derr.StatusCode)
+}
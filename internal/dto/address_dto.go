@@ -1,7 +1,11 @@
 package dto
 
 type AddressDto struct {
-	Cep        string `json:"cep"`
-	Localidade string `json:"localidade"`
-	Error      string `json:"erro"`
+	Cep        string   `json:"cep"`
+	Localidade string   `json:"localidade"`
+	Uf         string   `json:"uf"`
+	Lat        *float64 `json:"lat,omitempty"`
+	Lon        *float64 `json:"lon,omitempty"`
+	Error      string   `json:"erro"`
+	Cache      CacheDto `json:"-"`
 }
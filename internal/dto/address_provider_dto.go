@@ -0,0 +1,29 @@
+package dto
+
+type BrasilApiCepDto struct {
+	Cep          string               `json:"cep"`
+	State        string               `json:"state"`
+	City         string               `json:"city"`
+	Neighborhood string               `json:"neighborhood"`
+	Street       string               `json:"street"`
+	Location     brasilApiLocationDto `json:"location"`
+}
+
+type brasilApiLocationDto struct {
+	Coordinates brasilApiCoordinatesDto `json:"coordinates"`
+}
+
+type brasilApiCoordinatesDto struct {
+	Latitude  string `json:"latitude"`
+	Longitude string `json:"longitude"`
+}
+
+type AwesomeApiCepDto struct {
+	Cep      string `json:"cep"`
+	Address  string `json:"address"`
+	State    string `json:"state"`
+	District string `json:"district"`
+	City     string `json:"city"`
+	Lat      string `json:"lat"`
+	Lng      string `json:"lng"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+// CacheDto tells when a value was fetched from upstream and until when it may
+// be served, so handlers can set the Age and Cache-Control headers.
+type CacheDto struct {
+	Hit       bool
+	StoredAt  time.Time
+	ExpiresAt time.Time
+}
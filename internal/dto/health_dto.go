@@ -0,0 +1,12 @@
+package dto
+
+type HealthDto struct {
+	Status string                    `json:"status"`
+	Checks map[string]HealthCheckDto `json:"checks,omitempty"`
+}
+
+type HealthCheckDto struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
@@ -4,6 +4,17 @@ type ErroDto struct {
 	Msg string `json:"msg"`
 }
 
+// ProblemDto is an RFC 7807 problem details body. TraceID points to the
+// request's trace in Zipkin.
+type ProblemDto struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+	TraceID  string `json:"trace_id,omitempty"`
+}
+
 type ZipcodeBodyDto struct {
 	Cep string `json:"cep"`
 }
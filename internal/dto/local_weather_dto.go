@@ -1,8 +1,19 @@
 package dto
 
 type LocalWeatherDto struct {
-	Locale string  `json:"city"`
-	TempC  float64 `json:"temp_c"`
-	TempF  float64 `json:"temp_f"`
-	TempK  float64 `json:"temp_k"`
+	Locale   string       `json:"city"`
+	TempC    float64      `json:"temp_C"`
+	TempF    float64      `json:"temp_F"`
+	TempK    float64      `json:"temp_K"`
+	Location *LocationDto `json:"location,omitempty"`
+}
+
+// LocationDto is the place the weather provider resolved the address to,
+// returned so the caller can verify the temperature is from the right city.
+type LocationDto struct {
+	Name     string  `json:"name"`
+	Region   string  `json:"region"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	Provider string  `json:"provider"`
 }
@@ -1,6 +1,18 @@
 package dto
 
+// WeatherDto is the current weather as returned by every WeatherProvider,
+// along with the place the provider resolved the address to.
 type WeatherDto struct {
+	Location string   `json:"location"`
+	Region   string   `json:"region"`
+	Lat      float64  `json:"lat"`
+	Lon      float64  `json:"lon"`
+	TempC    float64  `json:"temp_c"`
+	Provider string   `json:"provider"`
+	Cache    CacheDto `json:"-"`
+}
+
+type WeatherApiDto struct {
 	Location weatherLocationDto
 	Current  weatherCurrentDto
 }
@@ -10,6 +22,29 @@ type weatherCurrentDto struct {
 }
 
 type weatherLocationDto struct {
-	Name   string `json:"name"`
-	Region string `json:"region"`
+	Name   string  `json:"name"`
+	Region string  `json:"region"`
+	Lat    float64 `json:"lat"`
+	Lon    float64 `json:"lon"`
+}
+
+type OpenMeteoGeocodingDto struct {
+	Results []openMeteoPlaceDto `json:"results"`
+}
+
+type openMeteoPlaceDto struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Admin1    string  `json:"admin1"`
+}
+
+type OpenMeteoForecastDto struct {
+	Latitude  float64             `json:"latitude"`
+	Longitude float64             `json:"longitude"`
+	Current   openMeteoCurrentDto `json:"current"`
+}
+
+type openMeteoCurrentDto struct {
+	Temperature2m float64 `json:"temperature_2m"`
 }
@@ -6,6 +6,7 @@ import (
 	"math"
 	"strings"
 
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/domainerr"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
 )
 
@@ -57,7 +58,7 @@ func (z *localWeatherEntity) IsValid() error {
 	}
 
 	if z.TempC() > 58 || z.tempC < -89 {
-		return errors.New("temperature is outside the earth range")
+		return domainerr.ErrInvalidTemperature
 	}
 	return nil
 }
@@ -1,10 +1,10 @@
 package entity
 
 import (
-	"errors"
 	"log/slog"
 	"regexp"
 
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/domainerr"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
 )
 
@@ -34,7 +34,7 @@ func (z *zipcodeEntity) IsValid() error {
 	var re = regexp.MustCompile(`^[0-9]{8}$`)
 
 	if !re.MatchString(z.zipcode) {
-		return errors.New("invalid zipcode")
+		return domainerr.ErrInvalidZipcode
 	}
 	return nil
 }
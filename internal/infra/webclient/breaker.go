@@ -0,0 +1,321 @@
+package webclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned by Do without calling the upstream while the
+// breaker of Host is open. It matches ErrCircuitOpen with errors.Is.
+type CircuitOpenError struct {
+	Host       string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "circuit breaker is open for " + e.Host + ", retry in " + e.RetryAfter.Round(time.Second).String()
+}
+
+// RetryAfterHeader is the remaining cool-down in whole seconds, rounded up
+// and at least 1, for a Retry-After header.
+func (e *CircuitOpenError) RetryAfterHeader() string {
+	return strconv.Itoa(max(int((e.RetryAfter+time.Second-1)/time.Second), 1))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerPolicy opens the breaker of a host when at least FailureRate of its
+// last WindowSize requests failed, once MinRequests were seen. After
+// OpenTimeout, HalfOpenRequests probes are let through: a success closes the
+// breaker, a failure opens it again.
+type BreakerPolicy struct {
+	FailureRate      float64
+	MinRequests      int
+	WindowSize       int
+	OpenTimeout      time.Duration
+	HalfOpenRequests int
+}
+
+var DefaultBreakerPolicy = BreakerPolicy{
+	FailureRate:      0.5,
+	MinRequests:      10,
+	WindowSize:       20,
+	OpenTimeout:      30 * time.Second,
+	HalfOpenRequests: 1,
+}
+
+// halfOpenRetryAfter is what the requests rejected while half-open are told
+// to wait, instead of retrying right away.
+const halfOpenRetryAfter = time.Second
+
+type transition struct {
+	from State
+	to   State
+}
+
+type breaker struct {
+	mu     sync.Mutex
+	host   string
+	policy BreakerPolicy
+	state  State
+
+	outcomes []bool
+	next     int
+	failures int
+
+	openedAt time.Time
+	probes   int
+}
+
+// Breakers keeps one breaker per host. A zero Policy uses the
+// DefaultBreakerPolicy of the time each host's breaker is created.
+type Breakers struct {
+	Policy BreakerPolicy
+
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+// DefaultBreakers is shared by the webclients created without WithBreakers.
+var DefaultBreakers = NewBreakers(BreakerPolicy{})
+
+var (
+	reportedMu sync.Mutex
+	reported   []*Breakers
+)
+
+// NewBreakers returns an empty registry. Only DefaultBreakers and the
+// registries given to ReportBreakers show up in the circuit_breaker.state
+// gauge, so short-lived registries are not kept alive by it.
+func NewBreakers(p BreakerPolicy) *Breakers {
+	return &Breakers{Policy: p}
+}
+
+// ReportBreakers adds r to the circuit_breaker.state gauge for the rest of
+// the process. It is meant for long-lived registries other than
+// DefaultBreakers.
+func ReportBreakers(r *Breakers) {
+
+	reportedMu.Lock()
+	defer reportedMu.Unlock()
+
+	if r != nil && r != DefaultBreakers && !slices.Contains(reported, r) {
+		reported = append(reported, r)
+	}
+}
+
+// WithBreakers makes the webclient use r instead of DefaultBreakers. A nil r
+// leaves the requests out of any breaker, e.g. for health probes.
+func WithBreakers(r *Breakers) Option {
+	return func(w *webClient) {
+		w.breakers = r
+	}
+}
+
+// Reset forgets every host, closing their breakers.
+func (r *Breakers) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hosts = nil
+}
+
+// get returns the breaker shared by every request to host.
+func (r *Breakers) get(host string) *breaker {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.hosts[host]
+	if !ok {
+		policy := r.Policy
+		if policy == (BreakerPolicy{}) {
+			policy = DefaultBreakerPolicy
+		}
+		b = &breaker{host: host, policy: policy}
+		if r.hosts == nil {
+			r.hosts = map[string]*breaker{}
+		}
+		r.hosts[host] = b
+	}
+
+	return b
+}
+
+// allow tells whether a request may go through, moving an open breaker to
+// half-open once its cool-down is over.
+func (b *breaker) allow(now time.Time) (*transition, error) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var tr *transition
+
+	if b.state == StateOpen {
+		wait := b.openedAt.Add(b.policy.OpenTimeout).Sub(now)
+		if wait > 0 {
+			return nil, &CircuitOpenError{Host: b.host, RetryAfter: wait}
+		}
+		tr = b.moveTo(StateHalfOpen, now)
+	}
+
+	if b.state == StateHalfOpen {
+		if b.probes >= max(b.policy.HalfOpenRequests, 1) {
+			// the probes in flight settle the state soon, either way
+			return tr, &CircuitOpenError{Host: b.host, RetryAfter: halfOpenRetryAfter}
+		}
+		b.probes++
+	}
+
+	return tr, nil
+}
+
+// record adds the outcome of an allowed request.
+func (b *breaker) record(success bool, now time.Time) *transition {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.probes--
+		if success {
+			return b.moveTo(StateClosed, now)
+		}
+		return b.moveTo(StateOpen, now)
+	}
+
+	if b.state != StateClosed {
+		return nil
+	}
+
+	size := max(b.policy.WindowSize, 1)
+	if len(b.outcomes) < size {
+		b.outcomes = append(b.outcomes, success)
+	} else {
+		if !b.outcomes[b.next] {
+			b.failures--
+		}
+		b.outcomes[b.next] = success
+		b.next = (b.next + 1) % size
+	}
+	if !success {
+		b.failures++
+	}
+
+	if len(b.outcomes) >= b.policy.MinRequests && float64(b.failures)/float64(len(b.outcomes)) >= b.policy.FailureRate {
+		return b.moveTo(StateOpen, now)
+	}
+
+	return nil
+}
+
+// release gives back a half-open probe whose outcome says nothing about the
+// upstream, e.g. a request cancelled by the caller.
+func (b *breaker) release() {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+func (b *breaker) moveTo(s State, now time.Time) *transition {
+
+	tr := &transition{from: b.state, to: s}
+
+	b.state = s
+	b.outcomes = b.outcomes[:0]
+	b.next = 0
+	b.failures = 0
+	b.probes = 0
+	if s == StateOpen {
+		b.openedAt = now
+	}
+
+	return tr
+}
+
+func (b *breaker) current() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// failed tells whether an outcome counts against the upstream: transport
+// errors, throttling and server errors.
+func failed(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+func recordTransition(span trace.Span, host string, tr *transition) {
+	if tr == nil {
+		return
+	}
+	span.AddEvent("circuit_breaker.state_change", trace.WithAttributes(
+		semconv.ServerAddress(host),
+		attribute.String("circuit_breaker.from", tr.from.String()),
+		attribute.String("circuit_breaker.to", tr.to.String()),
+	))
+}
+
+func init() {
+	_, err := otel.Meter(instrumentationName).Int64ObservableGauge(
+		"circuit_breaker.state",
+		metric.WithDescription("State of the circuit breaker of each upstream host: 0 closed, 1 open, 2 half-open."),
+		metric.WithInt64Callback(func(ctx context.Context, o metric.Int64Observer) error {
+
+			reportedMu.Lock()
+			registries := append([]*Breakers{DefaultBreakers}, reported...)
+			reportedMu.Unlock()
+
+			for _, r := range registries {
+				r.mu.Lock()
+				for host, b := range r.hosts {
+					o.Observe(int64(b.current()), metric.WithAttributes(semconv.ServerAddress(host)))
+				}
+				r.mu.Unlock()
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+}
@@ -0,0 +1,138 @@
+package webclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/mockup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestBreaker(t *testing.T) {
+
+	now := time.Now()
+	b := &breaker{host: "api.weatherapi.com", policy: BreakerPolicy{
+		FailureRate:      0.5,
+		MinRequests:      4,
+		WindowSize:       4,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenRequests: 1,
+	}}
+
+	type Lote struct {
+		Success    bool
+		Transition *transition
+	}
+
+	// the rate is only checked once MinRequests outcomes were seen
+	table := []Lote{
+		{Success: false},
+		{Success: false},
+		{Success: true},
+		{Success: false, Transition: &transition{from: StateClosed, to: StateOpen}},
+	}
+
+	for _, item := range table {
+		tr, err := b.allow(now)
+		assert.Nil(t, tr)
+		assert.Nil(t, err)
+		assert.Equal(t, item.Transition, b.record(item.Success, now))
+	}
+
+	_, err := b.allow(now.Add(5 * time.Second))
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, "5", err.(*CircuitOpenError).RetryAfterHeader())
+
+	// after the cool-down a single probe goes through
+	tr, err := b.allow(now.Add(10 * time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, &transition{from: StateOpen, to: StateHalfOpen}, tr)
+
+	// the others are told to wait for the probe, not to retry right away
+	_, err = b.allow(now.Add(10 * time.Second))
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, "1", err.(*CircuitOpenError).RetryAfterHeader())
+
+	// a failed probe opens it again, a successful one closes it
+	assert.Equal(t, &transition{from: StateHalfOpen, to: StateOpen}, b.record(false, now.Add(10*time.Second)))
+
+	_, err = b.allow(now.Add(15 * time.Second))
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+
+	_, err = b.allow(now.Add(20 * time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, &transition{from: StateHalfOpen, to: StateClosed}, b.record(true, now.Add(20*time.Second)))
+	assert.Equal(t, StateClosed, b.current())
+}
+
+func TestWebclientCircuitBreaker(t *testing.T) {
+
+	breakers := NewBreakers(BreakerPolicy{FailureRate: 1, MinRequests: 2, WindowSize: 2, OpenTimeout: time.Minute, HalfOpenRequests: 1})
+
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+	mockRoundTripper := new(mockup.MockRoundTripper)
+	mockRoundTripper.On("RoundTrip", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}, nil)
+	mockClient := &http.Client{Transport: mockRoundTripper}
+
+	do := func(opts ...Option) error {
+		opts = append([]Option{WithRetryPolicy(NoRetry), WithBreakers(breakers)}, opts...)
+		wc, err := NewWebclient(context.Background(), mockClient, http.MethodGet, "https://api.weatherapi.com/v1", nil, opts...)
+		assert.Nil(t, err)
+		return wc.Do(func(p []byte) error { return nil })
+	}
+
+	assert.Contains(t, do().Error(), "Service Unavailable")
+	assert.Contains(t, do().Error(), "Service Unavailable")
+
+	// the third request fails fast, without reaching the upstream
+	err := do()
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	mockRoundTripper.AssertNumberOfCalls(t, "RoundTrip", 2)
+
+	spans := rec.Ended()
+	assert.Len(t, spans, 3)
+
+	events := spans[1].Events()
+	last := events[len(events)-1]
+	assert.Equal(t, "circuit_breaker.state_change", last.Name)
+	assert.Contains(t, last.Attributes, attribute.String("circuit_breaker.to", "open"))
+	assert.Equal(t, "circuit breaker is open for api.weatherapi.com, retry in 1m0s", spans[2].Status().Description)
+
+	// other registries and clients without breakers are not affected
+	assert.Contains(t, do(WithBreakers(nil)).Error(), "Service Unavailable")
+	assert.Contains(t, do(WithBreakers(NewBreakers(BreakerPolicy{}))).Error(), "Service Unavailable")
+
+	breakers.Reset()
+	assert.Contains(t, do().Error(), "Service Unavailable")
+	mockRoundTripper.AssertNumberOfCalls(t, "RoundTrip", 5)
+}
+
+func TestReportBreakers(t *testing.T) {
+
+	n := len(reported)
+
+	// new registries are not kept by the gauge unless reported
+	r := NewBreakers(BreakerPolicy{})
+	assert.Len(t, reported, n)
+
+	ReportBreakers(r)
+	ReportBreakers(r)
+	ReportBreakers(DefaultBreakers)
+	ReportBreakers(nil)
+	assert.Len(t, reported, n+1)
+}
@@ -0,0 +1,181 @@
+package webclient
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RetryPolicy retries idempotent requests that failed at the transport or got
+// one of RetryableStatus, waiting an exponential backoff with full jitter
+// between attempts. A Retry-After longer than MaxDelay stops the retries.
+//
+// With RequireRetryAfter only the responses carrying Retry-After are retried,
+// for upstreams that retry on their own and only ask for a retry when it is
+// worth it.
+type RetryPolicy struct {
+	MaxAttempts       int
+	BaseDelay         time.Duration
+	MaxDelay          time.Duration
+	RetryableStatus   []int
+	RequireRetryAfter bool
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+	RetryableStatus: []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// NoRetry makes a single attempt.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+type Option func(*webClient)
+
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(w *webClient) {
+		w.retry = p
+	}
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// next tells whether the attempt should be retried and how long to wait.
+func (p RetryPolicy) next(ctx context.Context, method string, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+
+	if attempt >= p.MaxAttempts || !idempotent(method) || ctx.Err() != nil {
+		return 0, false
+	}
+
+	if err == nil && !slices.Contains(p.RetryableStatus, resp.StatusCode) {
+		return 0, false
+	}
+
+	if p.RequireRetryAfter && (resp == nil || resp.Header.Get("Retry-After") == "") {
+		return 0, false
+	}
+
+	delay := p.BaseDelay << (attempt - 1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	if delay > 0 {
+		delay = rand.N(delay + 1)
+	}
+
+	if resp != nil {
+		if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if after > p.MaxDelay {
+				return 0, false
+			}
+			delay = max(delay, after)
+		}
+	}
+
+	return delay, true
+}
+
+// retryAfter parses both forms of the header, delay-seconds and HTTP-date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+
+	if v == "" {
+		return 0, false
+	}
+
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// discard drains the body of a response that will be retried, so the
+// connection can be reused.
+func discard(resp *http.Response) {
+	if resp != nil {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+	}
+}
+
+func recordAttempt(span trace.Span, attempt int, resp *http.Response, err error, delay time.Duration, retry bool) {
+
+	attrs := []attribute.KeyValue{
+		attribute.Int("http.attempt", attempt),
+		attribute.Bool("http.retry", retry),
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error.message", err.Error()))
+	} else {
+		attrs = append(attrs, semconv.HTTPResponseStatusCode(resp.StatusCode))
+	}
+	if retry {
+		attrs = append(attrs, attribute.Int64("http.retry.delay_ms", delay.Milliseconds()))
+	}
+
+	span.AddEvent("http.attempt", trace.WithAttributes(attrs...))
+}
+
+var retries = sync.OnceValue(func() metric.Int64Counter {
+	counter, err := otel.Meter(instrumentationName).Int64Counter(
+		"http.client.retries",
+		metric.WithDescription("Requests retried by the webclient."),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return counter
+})
+
+func recordRetry(ctx context.Context, host string, resp *http.Response) {
+
+	reason := "error"
+	if resp != nil {
+		reason = strconv.Itoa(resp.StatusCode)
+	}
+
+	retries().Add(ctx, 1, metric.WithAttributes(
+		semconv.ServerAddress(host),
+		attribute.String("retry.reason", reason),
+	))
+}
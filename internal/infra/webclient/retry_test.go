@@ -0,0 +1,113 @@
+package webclient_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/mockup"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWebclientRetry(t *testing.T) {
+
+	policy := webclient.DefaultRetryPolicy
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 50 * time.Millisecond
+
+	asked := policy
+	asked.RequireRetryAfter = true
+
+	// attempt is a response status, or 0 for a transport error
+	type attempt struct {
+		status     int
+		retryAfter string
+	}
+
+	type Lote struct {
+		Name     string
+		Method   string
+		Policy   *webclient.RetryPolicy
+		Attempts []attempt
+		Calls    int
+		Error    string
+	}
+
+	table := []Lote{
+		{Name: "recovers after 503", Method: http.MethodGet, Attempts: []attempt{{status: 503}, {status: 502}, {status: 200}}, Calls: 3},
+		{Name: "recovers after transport error", Method: http.MethodGet, Attempts: []attempt{{status: 0}, {status: 200}}, Calls: 2},
+		{Name: "gives up after max attempts", Method: http.MethodGet, Attempts: []attempt{{status: 504}, {status: 504}, {status: 504}}, Calls: 3, Error: "Gateway Timeout"},
+		{Name: "500 is not retried", Method: http.MethodGet, Attempts: []attempt{{status: 500}}, Calls: 1, Error: "Internal Server Error"},
+		{Name: "post is not retried", Method: http.MethodPost, Attempts: []attempt{{status: 503}}, Calls: 1, Error: "Service Unavailable"},
+		{Name: "honors retry-after", Method: http.MethodGet, Attempts: []attempt{{status: 429, retryAfter: "0"}, {status: 200}}, Calls: 2},
+		{Name: "only retries when asked", Method: http.MethodGet, Policy: &asked, Attempts: []attempt{{status: 503, retryAfter: "0"}, {status: 503}}, Calls: 2, Error: "Service Unavailable"},
+		{Name: "transport error not retried when asking", Method: http.MethodGet, Policy: &asked, Attempts: []attempt{{status: 0}}, Calls: 1, Error: "connection reset by peer"},
+		{Name: "retry-after over max delay", Method: http.MethodGet, Attempts: []attempt{{status: 429, retryAfter: "120"}}, Calls: 1, Error: "Too Many Requests"},
+	}
+
+	for _, item := range table {
+		t.Run(item.Name, func(t *testing.T) {
+
+			rec := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+			mockRoundTripper := new(mockup.MockRoundTripper)
+			for _, a := range item.Attempts {
+				if a.status == 0 {
+					mockRoundTripper.On("RoundTrip", mock.Anything).Return(nil, errors.New("connection reset by peer")).Once()
+					continue
+				}
+				resp := &http.Response{StatusCode: a.status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{}`))}
+				if a.retryAfter != "" {
+					resp.Header.Set("Retry-After", a.retryAfter)
+				}
+				mockRoundTripper.On("RoundTrip", mock.Anything).Return(resp, nil).Once()
+			}
+			mockClient := &http.Client{Transport: mockRoundTripper}
+
+			p := policy
+			if item.Policy != nil {
+				p = *item.Policy
+			}
+
+			wc, err := webclient.NewWebclient(context.Background(), mockClient, item.Method, "https://api.weatherapi.com/v1/current.json", nil, webclient.WithRetryPolicy(p), webclient.WithBreakers(nil))
+			assert.Nil(t, err)
+
+			err = wc.Do(func(p []byte) error { return nil })
+			if item.Error != "" {
+				assert.Contains(t, err.Error(), item.Error)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			mockRoundTripper.AssertNumberOfCalls(t, "RoundTrip", item.Calls)
+
+			spans := rec.Ended()
+			assert.Len(t, spans, 1)
+			var attempts int
+			for _, e := range spans[0].Events() {
+				if e.Name == "http.attempt" {
+					attempts++
+				}
+			}
+			assert.Equal(t, item.Calls, attempts)
+
+			attrs := attribute.NewSet(spans[0].Attributes()...)
+			resend, ok := attrs.Value("http.request.resend_count")
+			assert.Equal(t, item.Calls > 1, ok)
+			if ok {
+				assert.Equal(t, int64(item.Calls-1), resend.AsInt64())
+			}
+		})
+	}
+}
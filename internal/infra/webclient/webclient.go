@@ -3,21 +3,47 @@ package webclient
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const instrumentationName = "github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
+
+// StatusError is returned by Do when the upstream answers with a status other
+// than 200 OK.
+type StatusError struct {
+	Host       string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return e.Host + ": " + http.StatusText(e.StatusCode)
+}
+
 type webClient struct {
-	request *http.Request
-	client  *http.Client
+	request  *http.Request
+	client   *http.Client
+	retry    RetryPolicy
+	breakers *Breakers
 }
 
 func (w *webClient) Request() *http.Request {
 	return w.request
 }
 
-func NewWebclient(ctx context.Context, client *http.Client, method string, url string, query map[string]string) (*webClient, error) {
+func NewWebclient(ctx context.Context, client *http.Client, method string, url string, query map[string]string, opts ...Option) (*webClient, error) {
 
 	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
@@ -27,7 +53,7 @@ func NewWebclient(ctx context.Context, client *http.Client, method string, url s
 
 	if ctx != nil {
 		req = req.WithContext(ctx)
-		slog.Debug("[Context Added]")
+		slog.DebugContext(ctx, "[Context Added]")
 	}
 
 	if query != nil {
@@ -38,21 +64,86 @@ func NewWebclient(ctx context.Context, client *http.Client, method string, url s
 		req.URL.RawQuery = q.Encode()
 	}
 
-	return &webClient{
-		request: req,
-		client:  client,
-	}, nil
+	w := &webClient{
+		request:  req,
+		client:   client,
+		retry:    DefaultRetryPolicy,
+		breakers: DefaultBreakers,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w, nil
 }
 
 func (w *webClient) Do(ret func([]byte) error) error {
 
-	slog.Debug("[http client Do host]", "host", w.request.URL.Host)
-	slog.Debug("[http client Do full url]", "url", w.request.URL)
+	ctx, span := otel.Tracer(instrumentationName).Start(w.request.Context(), w.request.Method+" "+w.request.URL.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(requestAttributes(w.request)...),
+	)
+	defer span.End()
+
+	w.request = w.request.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(w.request.Header))
+
+	slog.DebugContext(ctx, "[http client Do host]", "host", w.request.URL.Host)
+	slog.DebugContext(ctx, "[http client Do full url]", "url", redactedURL(w.request.URL))
+
+	var b *breaker
+	if w.breakers != nil {
+		b = w.breakers.get(w.request.URL.Host)
+		tr, err := b.allow(time.Now())
+		recordTransition(span, w.request.URL.Host, tr)
+		if err != nil {
+			slog.DebugContext(ctx, "[http client Do rejected]", "error", err.Error())
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+	}
+
+	var resp *http.Response
+	var err error
+	for attempt := 1; ; attempt++ {
+
+		resp, err = w.client.Do(w.request)
+		if err != nil {
+			err = redactedError(err, w.request.URL)
+		}
+
+		delay, retry := w.retry.next(ctx, w.request.Method, attempt, resp, err)
+		recordAttempt(span, attempt, resp, err, delay, retry)
+		if !retry {
+			break
+		}
+
+		slog.DebugContext(ctx, "[http client Do retry]", "host", w.request.URL.Host, "attempt", attempt, "delay", delay)
+		recordRetry(ctx, w.request.URL.Host, resp)
+		discard(resp)
+		span.SetAttributes(attribute.Int("http.request.resend_count", attempt))
+
+		if errWait := wait(ctx, delay); errWait != nil {
+			err = errWait
+			resp = nil
+			break
+		}
+	}
+
+	switch {
+	case b == nil:
+	case errors.Is(err, context.Canceled):
+		b.release()
+	default:
+		recordTransition(span, w.request.URL.Host, b.record(!failed(resp, err), time.Now()))
+	}
 
-	resp, err := w.client.Do(w.request)
 	if err != nil {
-		slog.Debug("[http Client Do failed]", "error", err.Error())
-		return errors.New("error to execute http request: " + w.request.URL.Host)
+		slog.DebugContext(ctx, "[http Client Do failed]", "error", err.Error())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to execute http request to %s: %w", w.request.URL.Host, err)
 	}
 	defer resp.Body.Close()
 
@@ -61,18 +152,78 @@ func (w *webClient) Do(ret func([]byte) error) error {
 		body = nil
 	}()
 	if err != nil {
-		slog.Error("[io.ReadAll failed]", "error", err.Error())
+		slog.ErrorContext(ctx, "[io.ReadAll failed]", "error", err.Error())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
-	slog.Debug("[http client Do status]", "status", resp.Status)
-	slog.Debug("[http client Do statuscode]", "code", resp.StatusCode)
+	slog.DebugContext(ctx, "[http client Do status]", "status", resp.Status)
+	slog.DebugContext(ctx, "[http client Do statuscode]", "code", resp.StatusCode)
+
+	span.SetAttributes(
+		semconv.HTTPResponseStatusCode(resp.StatusCode),
+		semconv.HTTPResponseBodySize(len(body)),
+	)
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
 
 	if resp.StatusCode != http.StatusOK {
-		return errors.New(w.request.URL.Host + ": " + http.StatusText(resp.StatusCode))
+		return &StatusError{Host: w.request.URL.Host, StatusCode: resp.StatusCode}
+	}
+
+	slog.DebugContext(ctx, "[http client Do body]", "body", body)
+
+	err = ret(body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
+
+// redactedURL leaves out the query string and the user info, since they may
+// carry credentials such as the WeatherAPI key. It is what url.full and the
+// logs get, the logs being exported to the collector as well.
+func redactedURL(u *url.URL) string {
+	r := *u
+	r.RawQuery = ""
+	r.User = nil
+	return r.String()
+}
+
+// redactedError keeps the query string out of the *url.Error of a transport
+// failure, which carries the full URL and ends up in spans and logs.
+func redactedError(err error, u *url.URL) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		ue.URL = redactedURL(u)
+	}
+	return err
+}
+
+func requestAttributes(req *http.Request) []attribute.KeyValue {
+
+	u := req.URL
+
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.URLFull(redactedURL(u)),
+		semconv.ServerAddress(u.Hostname()),
 	}
 
-	slog.Debug("[http client Do body]", "body", body)
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.ServerPort(p))
+	}
 
-	return ret(body)
+	return attrs
 }
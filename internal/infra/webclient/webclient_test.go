@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +17,13 @@ import (
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestNewWebclient(t *testing.T) {
@@ -51,7 +62,7 @@ func TestNewWebclient(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
-	wc, err := webclient.NewWebclient(ctx, mockClient, http.MethodGet, "https://dummy.restapiexample.com/api/v1/employee/1", urlQuery)
+	wc, err := webclient.NewWebclient(ctx, mockClient, http.MethodGet, "https://dummy.restapiexample.com/api/v1/employee/1", urlQuery, webclient.WithBreakers(nil))
 	assert.Nil(t, err)
 
 	var w ReqresResponse
@@ -101,7 +112,7 @@ func TestNewWebclientJsonError(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
-	wc, err := webclient.NewWebclient(ctx, mockClient, http.MethodGet, "https://dummy.restapiexample.com/api/v1/employee/1", urlQuery)
+	wc, err := webclient.NewWebclient(ctx, mockClient, http.MethodGet, "https://dummy.restapiexample.com/api/v1/employee/1", urlQuery, webclient.WithBreakers(nil))
 	assert.Nil(t, err)
 
 	var w ReqresResponse
@@ -148,7 +159,7 @@ func TestNewWebclientHttpStatusError(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
-	wc, err := webclient.NewWebclient(ctx, mockClient, http.MethodGet, "https://dummy.restapiexample.com/api/v1/employee/1", urlQuery)
+	wc, err := webclient.NewWebclient(ctx, mockClient, http.MethodGet, "https://dummy.restapiexample.com/api/v1/employee/1", urlQuery, webclient.WithBreakers(nil))
 	assert.Nil(t, err)
 
 	var w ReqresResponse
@@ -161,3 +172,93 @@ func TestNewWebclientHttpStatusError(t *testing.T) {
 	assert.Contains(t, err.Error(), "Not Found")
 	assert.Equal(t, http.MethodGet, wc.Request().Method)
 }
+
+func TestNewWebclientClientSpan(t *testing.T) {
+
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	mockRoundTripper := new(mockup.MockRoundTripper)
+	mockClient := &http.Client{Transport: mockRoundTripper}
+
+	var traceparent string
+	mockRoundTripper.On("RoundTrip", mock.Anything).Run(func(args mock.Arguments) {
+		traceparent = args.Get(0).(*http.Request).Header.Get("traceparent")
+	}).Return(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       io.NopCloser(bytes.NewReader([]byte(`unavailable`))),
+	}, nil)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+	// the logs are exported to the collector too, the key must not reach them
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	wc, err := webclient.NewWebclient(ctx, mockClient, http.MethodGet, "https://api.weatherapi.com/v1/current.json", map[string]string{"key": "secret"}, webclient.WithRetryPolicy(webclient.NoRetry), webclient.WithBreakers(nil))
+	assert.Nil(t, err)
+
+	err = wc.Do(func(p []byte) error { return nil })
+	assert.Contains(t, err.Error(), "Service Unavailable")
+	parent.End()
+
+	spans := rec.Ended()
+	assert.Len(t, spans, 2)
+
+	span := spans[0]
+	attrs := attribute.NewSet(span.Attributes()...)
+	value := func(key attribute.Key) string {
+		v, _ := attrs.Value(key)
+		return v.Emit()
+	}
+
+	assert.Equal(t, "GET api.weatherapi.com", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Contains(t, traceparent, span.SpanContext().SpanID().String())
+	assert.Equal(t, "https://api.weatherapi.com/v1/current.json", value("url.full"))
+	assert.Equal(t, "api.weatherapi.com", value("server.address"))
+	assert.Equal(t, "443", value("server.port"))
+	assert.Equal(t, "503", value("http.response.status_code"))
+	assert.Equal(t, "11", value("http.response.body.size"))
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	assert.Contains(t, logs.String(), "url=https://api.weatherapi.com/v1/current.json")
+	assert.NotContains(t, logs.String(), "secret")
+
+	// a transport error carries the full URL, query string included
+	failingRoundTripper := new(mockup.MockRoundTripper)
+	failingRoundTripper.On("RoundTrip", mock.Anything).Return(nil, errors.New("connection refused"))
+
+	wc, err = webclient.NewWebclient(context.Background(), &http.Client{Transport: failingRoundTripper}, http.MethodGet, "https://api.weatherapi.com/v1/current.json", map[string]string{"key": "secret"}, webclient.WithRetryPolicy(webclient.NoRetry), webclient.WithBreakers(nil))
+	assert.Nil(t, err)
+
+	err = wc.Do(func(p []byte) error { return nil })
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.NotContains(t, err.Error(), "secret")
+
+	spans = rec.Ended()
+	assert.Len(t, spans, 3)
+
+	span = spans[2]
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Contains(t, span.Status().Description, "https://api.weatherapi.com/v1/current.json")
+	assert.NotContains(t, span.Status().Description, "secret")
+	assert.True(t, slices.ContainsFunc(span.Events(), func(event sdktrace.Event) bool {
+		set := attribute.NewSet(event.Attributes...)
+		v, _ := set.Value("error.message")
+		return event.Name == "http.attempt" && strings.Contains(v.Emit(), "connection refused")
+	}))
+	for _, event := range span.Events() {
+		for _, kv := range event.Attributes {
+			assert.NotContains(t, kv.Value.Emit(), "secret", event.Name+" "+string(kv.Key))
+		}
+	}
+
+	assert.Contains(t, logs.String(), "connection refused")
+	assert.NotContains(t, logs.String(), "secret")
+}
@@ -0,0 +1,45 @@
+package webserver
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+const (
+	BaggageTenant    = "tenant"
+	BaggageClientApp = "client.app"
+)
+
+var baggageHeaders = map[string]string{
+	"X-Tenant-Id":  BaggageTenant,
+	"X-Client-App": BaggageClientApp,
+}
+
+// baggageFromHeaders adds the business keys sent by the caller to the
+// baggage, so they are propagated to every downstream service.
+func baggageFromHeaders(ctx context.Context, h http.Header) context.Context {
+
+	bag := baggage.FromContext(ctx)
+	for header, key := range baggageHeaders {
+		value := h.Get(header)
+		if value == "" {
+			continue
+		}
+
+		m, err := baggage.NewMemberRaw(key, value)
+		if err != nil {
+			slog.WarnContext(ctx, "[invalid baggage member]", "key", key, "error", err.Error())
+			continue
+		}
+
+		bag, err = bag.SetMember(m)
+		if err != nil {
+			slog.WarnContext(ctx, "[baggage set member]", "key", key, "error", err.Error())
+		}
+	}
+
+	return baggage.ContextWithBaggage(ctx, bag)
+}
@@ -0,0 +1,67 @@
+package webserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestBaggageFromHeaders(t *testing.T) {
+
+	incoming, _ := baggage.NewMemberRaw("session", "abc")
+	bag, _ := baggage.New(incoming)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	h := http.Header{}
+	h.Set("X-Tenant-Id", "acme corp")
+	h.Set("X-Client-App", "web")
+
+	bag = baggage.FromContext(baggageFromHeaders(ctx, h))
+	assert.Equal(t, "acme corp", bag.Member(BaggageTenant).Value())
+	assert.Equal(t, "web", bag.Member(BaggageClientApp).Value())
+	assert.Equal(t, "abc", bag.Member("session").Value())
+
+	bag = baggage.FromContext(baggageFromHeaders(context.Background(), http.Header{}))
+	assert.Equal(t, 0, bag.Len())
+}
+
+// startBaggage keeps the baggage each span started with, which is all a
+// baggage span processor gets to see.
+type startBaggage struct {
+	started map[string]baggage.Baggage
+}
+
+func (s *startBaggage) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan) {
+	s.started[span.Name()] = baggage.FromContext(ctx)
+}
+
+func (s *startBaggage) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (s *startBaggage) Shutdown(context.Context) error   { return nil }
+func (s *startBaggage) ForceFlush(context.Context) error { return nil }
+
+func TestWebServerBaggageFromHeaders(t *testing.T) {
+
+	proc := &startBaggage{started: map[string]baggage.Baggage{}}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(proc))
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+
+	ws := NewWebServer("0", WithBaggageFromHeaders())
+	ws.AddHandler("POST /zipcode/", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodPost, "/zipcode/", nil)
+	req.Header.Set("X-Tenant-Id", "acme")
+	req.Header.Set("X-Client-App", "web")
+	ws.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	bag, ok := proc.started["POST /zipcode/"]
+	assert.True(t, ok)
+	assert.Equal(t, "acme", bag.Member(BaggageTenant).Value())
+	assert.Equal(t, "web", bag.Member(BaggageClientApp).Value())
+}
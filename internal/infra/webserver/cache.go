@@ -0,0 +1,43 @@
+package webserver
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+)
+
+// writeCacheHeaders sets Cache-Control and Age for a response built from the
+// given lookups: it stays fresh until the first of them expires and is as old
+// as the oldest of them. Nothing is set when a lookup was not cached.
+func writeCacheHeaders(w http.ResponseWriter, now time.Time, entries ...dto.CacheDto) {
+
+	var storedAt, expiresAt time.Time
+	hit := false
+
+	for _, e := range entries {
+		if e.StoredAt.IsZero() {
+			return
+		}
+		if storedAt.IsZero() || e.StoredAt.Before(storedAt) {
+			storedAt = e.StoredAt
+		}
+		if expiresAt.IsZero() || e.ExpiresAt.Before(expiresAt) {
+			expiresAt = e.ExpiresAt
+		}
+		hit = hit || e.Hit
+	}
+
+	if storedAt.IsZero() {
+		return
+	}
+
+	age := max(now.Sub(storedAt), 0)
+	maxAge := max(expiresAt.Sub(now), 0) + age
+
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+	if hit {
+		w.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+	}
+}
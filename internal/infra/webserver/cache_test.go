@@ -0,0 +1,47 @@
+package webserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCacheHeaders(t *testing.T) {
+
+	now := time.Now()
+
+	type Lote struct {
+		Name         string
+		Entries      []dto.CacheDto
+		CacheControl string
+		Age          string
+	}
+
+	table := []Lote{
+		{
+			Name:         "fresh lookups",
+			Entries:      []dto.CacheDto{{StoredAt: now, ExpiresAt: now.Add(24 * time.Hour)}, {StoredAt: now, ExpiresAt: now.Add(5 * time.Minute)}},
+			CacheControl: "public, max-age=300",
+		},
+		{
+			Name:         "weather served from cache",
+			Entries:      []dto.CacheDto{{StoredAt: now, ExpiresAt: now.Add(24 * time.Hour)}, {Hit: true, StoredAt: now.Add(-2 * time.Minute), ExpiresAt: now.Add(3 * time.Minute)}},
+			CacheControl: "public, max-age=300",
+			Age:          "120",
+		},
+		{
+			Name:    "cache disabled",
+			Entries: []dto.CacheDto{{}, {StoredAt: now, ExpiresAt: now.Add(5 * time.Minute)}},
+		},
+	}
+
+	for _, item := range table {
+		rec := httptest.NewRecorder()
+		writeCacheHeaders(rec, now, item.Entries...)
+		assert.Equal(t, item.CacheControl, rec.Header().Get("Cache-Control"), item.Name)
+		assert.Equal(t, item.Age, rec.Header().Get("Age"), item.Name)
+	}
+}
@@ -0,0 +1,156 @@
+package webserver_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webserver"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+// upstreams sends every request of the services to a local server, by host.
+type upstreams map[string]*url.URL
+
+func (u upstreams) RoundTrip(r *http.Request) (*http.Response, error) {
+
+	target, ok := u[r.URL.Host]
+	if !ok {
+		return nil, errors.New("unexpected upstream " + r.URL.Host)
+	}
+
+	r = r.Clone(r.Context())
+	r.URL.Scheme = target.Scheme
+	r.URL.Host = target.Host
+	r.Host = ""
+
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// fakeUpstreams answers as ViaCEP and WeatherAPI would for a few zip codes.
+func fakeUpstreams() http.Handler {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /ws/{cep}/json/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PathValue("cep") {
+		case "01001000":
+			w.Write([]byte(`{"cep":"01001-000","localidade":"São Paulo","uf":"SP"}`))
+		case "69900000":
+			w.Write([]byte(`{"cep":"69900-000","localidade":"Rio Branco","uf":"AC"}`))
+		case "88888888":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"erro":"true"}`))
+		}
+	})
+
+	mux.HandleFunc("GET /v1/current.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("q") {
+		case "São Paulo,SP,Brazil":
+			w.Write([]byte(`{"location":{"name":"Sao Paulo","region":"Sao Paulo","lat":-23.53,"lon":-46.62},"current":{"temp_c":20}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	return mux
+}
+
+func TestContract(t *testing.T) {
+
+	t.Setenv("ADDRESS_PROVIDERS", usecase.AddressProviderViaCep)
+	t.Setenv("WEATHER_PROVIDERS", usecase.WeatherProviderWeatherApi)
+	t.Setenv("WEATHER_API_KEY", "contract")
+	t.Setenv("SERVICE_B_HOST", "service-b")
+	t.Setenv("SERVICE_B_PORT", "8081")
+	usecase.SetCache(usecase.NewMemoryCache(16))
+	webclient.DefaultBreakers.Reset()
+
+	fake := httptest.NewServer(fakeUpstreams())
+	defer fake.Close()
+
+	wsB := webserver.NewWebServer("0")
+	wsB.AddHandler("GET /zipcode/{zipcode}", webserver.GetWeatherByZipcodeHandler)
+	serviceB := httptest.NewServer(wsB.Handler())
+	defer serviceB.Close()
+
+	wsA := webserver.NewWebServer("0")
+	wsA.AddHandler("POST /zipcode/", webserver.GetZipcodeHandler)
+	serviceA := httptest.NewServer(wsA.Handler())
+	defer serviceA.Close()
+
+	fakeURL, _ := url.Parse(fake.URL)
+	serviceBURL, _ := url.Parse(serviceB.URL)
+
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = upstreams{
+		"viacep.com.br":      fakeURL,
+		"api.weatherapi.com": fakeURL,
+		"service-b:8081":     serviceBURL,
+	}
+	defer func() { http.DefaultClient.Transport = transport }()
+
+	// the test itself talks to the services directly
+	client := &http.Client{}
+
+	success := `{"city":"São Paulo","temp_C":20,"temp_F":68,"temp_K":293,"location":{"name":"Sao Paulo","region":"Sao Paulo","lat":-23.53,"lon":-46.62,"provider":"weatherapi"}}`
+
+	type Lote struct {
+		Name    string
+		Service string
+		Body    string
+		Zipcode string
+		Code    int
+		Resp    string
+	}
+
+	table := []Lote{
+		{Name: "found", Service: "a", Body: `{"cep":"01001000"}`, Code: http.StatusOK, Resp: success},
+		{Name: "short zipcode", Service: "a", Body: `{"cep":"0100100"}`, Code: http.StatusUnprocessableEntity, Resp: `{"msg":"invalid zipcode"}`},
+		{Name: "letters", Service: "a", Body: `{"cep":"0100100a"}`, Code: http.StatusUnprocessableEntity, Resp: `{"msg":"invalid zipcode"}`},
+		{Name: "not a string", Service: "a", Body: `{"cep":01001000}`, Code: http.StatusUnprocessableEntity, Resp: `{"msg":"invalid zipcode"}`},
+		{Name: "number", Service: "a", Body: `{"cep":1001000}`, Code: http.StatusUnprocessableEntity, Resp: `{"msg":"invalid zipcode"}`},
+		{Name: "malformed json", Service: "a", Body: `{"cep":`, Code: http.StatusUnprocessableEntity, Resp: `{"msg":"invalid zipcode"}`},
+		{Name: "not found", Service: "a", Body: `{"cep":"99999999"}`, Code: http.StatusNotFound, Resp: `{"msg":"can not find zipcode"}`},
+		{Name: "upstream down", Service: "a", Body: `{"cep":"69900000"}`, Code: http.StatusServiceUnavailable, Resp: `{"msg":"upstream unavailable"}`},
+		{Name: "found", Service: "b", Zipcode: "01001000", Code: http.StatusOK, Resp: success},
+		{Name: "short zipcode", Service: "b", Zipcode: "0100100", Code: http.StatusUnprocessableEntity, Resp: `{"msg":"invalid zipcode"}`},
+		{Name: "letters", Service: "b", Zipcode: "0100100a", Code: http.StatusUnprocessableEntity, Resp: `{"msg":"invalid zipcode"}`},
+		{Name: "not found", Service: "b", Zipcode: "99999999", Code: http.StatusNotFound, Resp: `{"msg":"can not find zipcode"}`},
+		{Name: "address upstream down", Service: "b", Zipcode: "88888888", Code: http.StatusServiceUnavailable, Resp: `{"msg":"upstream unavailable"}`},
+		{Name: "weather upstream fails", Service: "b", Zipcode: "69900000", Code: http.StatusServiceUnavailable, Resp: `{"msg":"upstream unavailable"}`},
+	}
+
+	for _, item := range table {
+		t.Run(item.Service+"/"+item.Name, func(t *testing.T) {
+
+			var resp *http.Response
+			var err error
+			if item.Service == "a" {
+				resp, err = client.Post(serviceA.URL+"/zipcode/", "application/json", strings.NewReader(item.Body))
+			} else {
+				resp, err = client.Get(serviceB.URL + "/zipcode/" + item.Zipcode)
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			assert.Nil(t, err)
+
+			assert.Equal(t, item.Code, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			assert.JSONEq(t, item.Resp, string(body))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package webserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/domainerr"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details, sent
+// instead of the legacy {"msg"} body when the client prefers it in Accept.
+const ProblemContentType = "application/problem+json"
+
+// writeError answers err with the status code and message of the contract,
+// both taken from domainerr. An open circuit breaker also sets Retry-After.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+
+	code := errorStatus(r.Context(), err)
+	slog.WarnContext(r.Context(), "[request failed]", "status", code, "error", err.Error())
+
+	var open *webclient.CircuitOpenError
+	if errors.As(err, &open) {
+		w.Header().Set("Retry-After", open.RetryAfterHeader())
+	}
+
+	writeErrorBody(w, r, code, domainerr.Message(err))
+}
+
+// writeErrorBody writes msg as problem details or as the legacy body,
+// following the request's Accept header.
+func writeErrorBody(w http.ResponseWriter, r *http.Request, code int, msg string) {
+
+	if !acceptsProblem(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&dto.ErroDto{Msg: msg})
+		return
+	}
+
+	problem := dto.ProblemDto{
+		Type:     "about:blank",
+		Title:    http.StatusText(code),
+		Status:   code,
+		Detail:   msg,
+		Instance: r.URL.RequestURI(),
+	}
+	if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+		problem.TraceID = sc.TraceID().String()
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&problem)
+}
+
+// acceptsProblem tells whether the Accept header ranks problem+json above
+// plain JSON. A missing header or wildcards keep the legacy body.
+func acceptsProblem(accept string) bool {
+
+	var problem, plain float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case ProblemContentType:
+			problem = max(problem, q)
+		case "application/json":
+			plain = max(plain, q)
+		}
+	}
+
+	return problem > 0 && problem >= plain
+}
+
+// errorStatus records err on the server span following domainerr.SpanStatus
+// and returns the status code to answer with.
+func errorStatus(ctx context.Context, err error) int {
+
+	span := trace.SpanFromContext(ctx)
+
+	code, desc := domainerr.SpanStatus(err)
+	if code == codes.Error {
+		span.RecordError(err)
+	}
+	span.SetStatus(code, desc)
+
+	return domainerr.HTTPStatus(err)
+}
@@ -0,0 +1,85 @@
+package webserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webserver"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWriteErrorNegotiation(t *testing.T) {
+
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+
+	ws := webserver.NewWebServer("0")
+	ws.AddHandler("GET /zipcode/{zipcode}", webserver.GetWeatherByZipcodeHandler)
+	ws.AddHandler("POST /zipcode/", webserver.GetZipcodeHandler, webserver.WithRouteMaxBodyBytes(16))
+
+	type Lote struct {
+		Accept  string
+		Problem bool
+	}
+
+	table := []Lote{
+		{Accept: "", Problem: false},
+		{Accept: "*/*", Problem: false},
+		{Accept: "application/json", Problem: false},
+		{Accept: "application/problem+json", Problem: true},
+		{Accept: "application/json, application/problem+json", Problem: true},
+		{Accept: "application/problem+json;q=0.5, application/json", Problem: false},
+		{Accept: "application/problem+json;q=0", Problem: false},
+	}
+
+	for _, item := range table {
+		req := httptest.NewRequest(http.MethodGet, "/zipcode/123?x=1", nil)
+		req.Header.Set("Accept", item.Accept)
+		resp := httptest.NewRecorder()
+		ws.Handler().ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, item.Accept)
+
+		if !item.Problem {
+			assert.Equal(t, "application/json", resp.Header().Get("Content-Type"), item.Accept)
+			assert.JSONEq(t, `{"msg":"invalid zipcode"}`, resp.Body.String(), item.Accept)
+			continue
+		}
+
+		assert.Equal(t, webserver.ProblemContentType, resp.Header().Get("Content-Type"), item.Accept)
+
+		var problem dto.ProblemDto
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&problem))
+		assert.Equal(t, "about:blank", problem.Type)
+		assert.Equal(t, "Unprocessable Entity", problem.Title)
+		assert.Equal(t, http.StatusUnprocessableEntity, problem.Status)
+		assert.Equal(t, "invalid zipcode", problem.Detail)
+		assert.Equal(t, "/zipcode/123?x=1", problem.Instance)
+
+		spans := rec.Ended()
+		assert.Equal(t, spans[len(spans)-1].SpanContext().TraceID().String(), problem.TraceID)
+	}
+
+	// the request limits negotiate the same way
+	req := httptest.NewRequest(http.MethodPost, "/zipcode/", strings.NewReader(`{"cep":"`+strings.Repeat("1", 32)+`"}`))
+	req.Header.Set("Accept", webserver.ProblemContentType)
+	resp := httptest.NewRecorder()
+	ws.Handler().ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	assert.Equal(t, webserver.ProblemContentType, resp.Header().Get("Content-Type"))
+
+	var problem dto.ProblemDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, problem.Status)
+	assert.Equal(t, "request body too large", problem.Detail)
+	assert.NotEmpty(t, problem.TraceID)
+}
@@ -0,0 +1,143 @@
+package webserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+
+	healthStatusOk       = "ok"
+	healthStatusFail     = "fail"
+	healthStatusDraining = "draining"
+
+	defaultHealthCheckTimeout = 2 * time.Second
+)
+
+type HealthCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// AddLivenessCheck registers a check served by /healthz. Keep them cheap and
+// local: a failing liveness probe restarts the container.
+func (s *WebServer) AddLivenessCheck(name string, check HealthCheck) {
+	s.livenessChecks = append(s.livenessChecks, namedCheck{name: name, check: check})
+	slog.Info("[liveness check added]", "name", name)
+}
+
+// AddReadinessCheck registers a check served by /readyz, e.g. the
+// reachability of an upstream.
+func (s *WebServer) AddReadinessCheck(name string, check HealthCheck) {
+	s.readinessChecks = append(s.readinessChecks, namedCheck{name: name, check: check})
+	slog.Info("[readiness check added]", "name", name)
+}
+
+// health serves the probes outside the instrumentation, so they do not add
+// server spans, metrics or access logs on every poll.
+func (s *WebServer) health(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.URL.Path {
+		case LivenessPath:
+			s.writeHealth(w, r, s.livenessChecks, false)
+		case ReadinessPath:
+			s.writeHealth(w, r, s.readinessChecks, s.draining.Load())
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (s *WebServer) writeHealth(w http.ResponseWriter, r *http.Request, checks []namedCheck, draining bool) {
+
+	report := dto.HealthDto{Status: healthStatusOk}
+	code := http.StatusOK
+
+	if draining {
+		report.Status = healthStatusDraining
+		code = http.StatusServiceUnavailable
+	} else if len(checks) > 0 {
+		report.Checks = runChecks(untracedContext(r.Context()), checks)
+		for _, c := range report.Checks {
+			if c.Status != healthStatusOk {
+				report.Status = healthStatusFail
+				code = http.StatusServiceUnavailable
+			}
+		}
+	}
+
+	if code != http.StatusOK {
+		slog.WarnContext(r.Context(), "[health check failed]", "path", r.URL.Path, "report", report)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&report)
+}
+
+func runChecks(ctx context.Context, checks []namedCheck) map[string]dto.HealthCheckDto {
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]dto.HealthCheckDto, len(checks))
+
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, defaultHealthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.check(ctx)
+
+			result := dto.HealthCheckDto{Status: healthStatusOk, Duration: time.Since(start).String()}
+			if err != nil {
+				result.Status = healthStatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[c.name] = result
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// untracedContext carries a non-sampled parent, so parent-based samplers
+// drop the client spans of the checks here and in the services they call.
+func untracedContext(ctx context.Context) context.Context {
+
+	var tid trace.TraceID
+	var sid trace.SpanID
+	rand.Read(tid[:])
+	rand.Read(sid[:])
+
+	return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: tid,
+		SpanID:  sid,
+	}))
+}
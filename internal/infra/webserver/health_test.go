@@ -0,0 +1,68 @@
+package webserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webserver"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWebServerHealth(t *testing.T) {
+
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+
+	upstream := errors.New("upstream down")
+
+	ws := webserver.NewWebServer("0")
+	ws.AddReadinessCheck("config", func(ctx context.Context) error { return nil })
+	ws.AddReadinessCheck("upstream", func(ctx context.Context) error {
+		// the checks run under a non-sampled parent, so their client spans are dropped
+		assert.False(t, trace.SpanContextFromContext(ctx).IsSampled())
+		return upstream
+	})
+
+	type Lote struct {
+		Path   string
+		Code   int
+		Status string
+		Checks int
+	}
+
+	table := []Lote{
+		{Path: webserver.LivenessPath, Code: http.StatusOK, Status: "ok", Checks: 0},
+		{Path: webserver.ReadinessPath, Code: http.StatusServiceUnavailable, Status: "fail", Checks: 2},
+	}
+
+	for _, item := range table {
+		resp := httptest.NewRecorder()
+		ws.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, item.Path, nil))
+		assert.Equal(t, item.Code, resp.Code)
+		assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+		var report dto.HealthDto
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&report))
+		assert.Equal(t, item.Status, report.Status)
+		assert.Len(t, report.Checks, item.Checks)
+
+		if item.Path == webserver.ReadinessPath {
+			assert.Equal(t, "ok", report.Checks["config"].Status)
+			assert.Equal(t, "fail", report.Checks["upstream"].Status)
+			assert.Equal(t, "upstream down", report.Checks["upstream"].Error)
+		}
+	}
+
+	assert.Empty(t, rec.Ended())
+}
@@ -0,0 +1,136 @@
+package webserver
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webserver"
+
+// statusRecorder keeps the status code and the body size written by the handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(p)
+	s.size += n
+	return n, err
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// instrument starts a server span for every request routed by mux, named
+// after the matched route pattern, and records the request duration. With
+// headerBaggage the business headers join the baggage before the span starts.
+func instrument(mux *http.ServeMux, headerBaggage bool, next http.Handler) http.Handler {
+
+	duration, err := otel.Meter(instrumentationName).Float64Histogram(
+		"http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP server requests."),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		start := time.Now()
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		if headerBaggage {
+			ctx = baggageFromHeaders(ctx, r.Header)
+		}
+
+		_, pattern := mux.Handler(r)
+		route := routeFromPattern(pattern)
+
+		name := r.Method
+		if route != "" {
+			name += " " + route
+		}
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLScheme(scheme),
+		}
+		if route != "" {
+			attrs = append(attrs, semconv.HTTPRoute(route))
+		}
+
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+			trace.WithAttributes(
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+				semconv.ServerAddress(r.Host),
+			),
+		)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		span.SetAttributes(
+			semconv.HTTPResponseStatusCode(rec.status),
+			semconv.HTTPResponseBodySize(rec.size),
+		)
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+
+		if duration != nil {
+			attrs = append(attrs, semconv.HTTPResponseStatusCode(rec.status))
+			duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+		}
+	})
+}
+
+// routeFromPattern drops the method and host of a ServeMux pattern,
+// e.g. "GET /zipcode/{zipcode}" becomes "/zipcode/{zipcode}".
+func routeFromPattern(pattern string) string {
+
+	if _, path, found := strings.Cut(pattern, " "); found {
+		pattern = strings.TrimSpace(path)
+	}
+
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+
+	return pattern
+}
@@ -0,0 +1,72 @@
+package webserver_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webserver"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWebServerInstrumentation(t *testing.T) {
+
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	ws := webserver.NewWebServer("0")
+	ws.AddHandler("GET /zipcode/{zipcode}", func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, trace.SpanContextFromContext(r.Context()).IsValid())
+		if r.PathValue("zipcode") == "00000000" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"city":"Campinas"}`))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/zipcode/13015100", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	ws.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	ws.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/zipcode/00000000", nil))
+	ws.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	spans := rec.Ended()
+	assert.Len(t, spans, 3)
+
+	ok := spans[0]
+	attrs := attribute.NewSet(ok.Attributes()...)
+	assert.Equal(t, "GET /zipcode/{zipcode}", ok.Name())
+	assert.Equal(t, trace.SpanKindServer, ok.SpanKind())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", ok.Parent().TraceID().String())
+	assert.Equal(t, "/zipcode/{zipcode}", attrValue(attrs, "http.route"))
+	assert.Equal(t, "GET", attrValue(attrs, "http.request.method"))
+	assert.Equal(t, "200", attrValue(attrs, "http.response.status_code"))
+	assert.Equal(t, "19", attrValue(attrs, "http.response.body.size"))
+	assert.Equal(t, codes.Unset, ok.Status().Code)
+
+	failed := spans[1]
+	assert.Equal(t, "500", attrValue(attribute.NewSet(failed.Attributes()...), "http.response.status_code"))
+	assert.Equal(t, codes.Error, failed.Status().Code)
+
+	notFound := spans[2]
+	assert.Equal(t, "GET", notFound.Name())
+	assert.Equal(t, "404", attrValue(attribute.NewSet(notFound.Attributes()...), "http.response.status_code"))
+	assert.Equal(t, codes.Unset, notFound.Status().Code)
+}
+
+func attrValue(set attribute.Set, key attribute.Key) string {
+	v, _ := set.Value(key)
+	return v.Emit()
+}
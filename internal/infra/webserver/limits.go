@@ -0,0 +1,56 @@
+package webserver
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// limitBody answers 413 when the declared Content-Length is over the limit
+// and caps the reader for chunked bodies, so the handler's decoder fails
+// with *http.MaxBytesError.
+func limitBody(limit int64, next http.Handler) http.Handler {
+
+	if limit <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.ContentLength > limit {
+			slog.WarnContext(r.Context(), "[request body too large]", "length", r.ContentLength, "limit", limit)
+			writeLimitError(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitConcurrency sheds the requests over the limit instead of queueing
+// them behind slow upstreams.
+func limitConcurrency(limit int, next http.Handler) http.Handler {
+
+	if limit <= 0 {
+		return next
+	}
+
+	sem := make(chan struct{}, limit)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			next.ServeHTTP(w, r)
+		default:
+			slog.WarnContext(r.Context(), "[too many concurrent requests]", "limit", limit)
+			w.Header().Set("Retry-After", "1")
+			writeLimitError(w, r, http.StatusServiceUnavailable, "server is busy")
+		}
+	})
+}
+
+func writeLimitError(w http.ResponseWriter, r *http.Request, code int, msg string) {
+	writeErrorBody(w, r, code, msg)
+}
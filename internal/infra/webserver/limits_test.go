@@ -0,0 +1,76 @@
+package webserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebServerMaxBodyBytes(t *testing.T) {
+
+	ws := webserver.NewWebServer("0", webserver.WithMaxBodyBytes(64))
+	ws.AddHandler("POST /zipcode/", webserver.GetZipcodeHandler, webserver.WithRouteMaxBodyBytes(16))
+	ws.AddHandler("POST /echo", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	body := `{"cep":"` + strings.Repeat("1", 32) + `"}`
+
+	// declared Content-Length over the limit
+	rec := httptest.NewRecorder()
+	ws.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/zipcode/", strings.NewReader(body)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.JSONEq(t, `{"msg":"request body too large"}`, rec.Body.String())
+
+	// chunked body, the limit is hit while decoding
+	req := httptest.NewRequest(http.MethodPost, "/zipcode/", strings.NewReader(body))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	ws.Handler().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// the server-wide limit applies to routes without their own
+	rec = httptest.NewRecorder()
+	ws.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body)))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = httptest.NewRecorder()
+	ws.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(strings.Repeat("x", 65))))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestWebServerMaxConcurrentRequests(t *testing.T) {
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	ws := webserver.NewWebServer("0", webserver.WithMaxConcurrentRequests(1))
+	ws.AddHandler("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := ws.Handler()
+
+	var wg sync.WaitGroup
+	first := httptest.NewRecorder()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-started
+
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, second.Code)
+	assert.Equal(t, "1", second.Header().Get("Retry-After"))
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, first.Code)
+}
@@ -0,0 +1,169 @@
+package webserver_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webserver"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWebServerMiddlewareOrder(t *testing.T) {
+
+	var calls []string
+	mark := func(name string) webserver.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	ws := webserver.NewWebServer("0")
+	ws.Use(mark("global-1"), mark("global-2"))
+	ws.AddHandler("GET /a", func(w http.ResponseWriter, r *http.Request) { calls = append(calls, "handler-a") },
+		webserver.WithRouteMiddleware(mark("route-a")))
+	ws.AddHandler("GET /b", func(w http.ResponseWriter, r *http.Request) { calls = append(calls, "handler-b") })
+
+	ws.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", nil))
+	assert.Equal(t, []string{"global-1", "global-2", "route-a", "handler-a"}, calls)
+
+	calls = nil
+	ws.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/b", nil))
+	assert.Equal(t, []string{"global-1", "global-2", "handler-b"}, calls)
+}
+
+func TestRecoverer(t *testing.T) {
+
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+
+	ws := webserver.NewWebServer("0")
+	ws.Use(webserver.Recoverer)
+	ws.AddHandler("GET /panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	resp := httptest.NewRecorder()
+	ws.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.JSONEq(t, `{"msg":"Internal Server Error"}`, resp.Body.String())
+
+	spans := rec.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, "exception", spans[0].Events()[0].Name)
+
+	// problem details are negotiated like any other error
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("Accept", webserver.ProblemContentType)
+	resp = httptest.NewRecorder()
+	ws.Handler().ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, webserver.ProblemContentType, resp.Header().Get("Content-Type"))
+
+	var problem dto.ProblemDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, http.StatusInternalServerError, problem.Status)
+	assert.Equal(t, rec.Ended()[1].SpanContext().TraceID().String(), problem.TraceID)
+}
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	var fromCtx string
+	ws := webserver.NewWebServer("0")
+	ws.Use(webserver.RequestID, webserver.AccessLog)
+	ws.AddHandler("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+		fromCtx = webserver.RequestIDFromContext(r.Context())
+		w.Write([]byte("pong"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(webserver.RequestIDHeader, "abc-123")
+	resp := httptest.NewRecorder()
+	ws.Handler().ServeHTTP(resp, req)
+
+	assert.Equal(t, "abc-123", resp.Header().Get(webserver.RequestIDHeader))
+	assert.Equal(t, "abc-123", fromCtx)
+	assert.Contains(t, buf.String(), "[access]")
+	assert.Contains(t, buf.String(), "status=200")
+	assert.Contains(t, buf.String(), "bytes=4")
+	assert.Contains(t, buf.String(), "request_id=abc-123")
+
+	req = httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(webserver.RequestIDHeader, "not valid\nid")
+	resp = httptest.NewRecorder()
+	ws.Handler().ServeHTTP(resp, req)
+
+	assert.Len(t, resp.Header().Get(webserver.RequestIDHeader), 32)
+	assert.Equal(t, resp.Header().Get(webserver.RequestIDHeader), fromCtx)
+}
+
+func TestAccessLogPanic(t *testing.T) {
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	ws := webserver.NewWebServer("0")
+	ws.Use(webserver.RequestID, webserver.AccessLog, webserver.Recoverer)
+	ws.AddHandler("GET /panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	resp := httptest.NewRecorder()
+	ws.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Contains(t, buf.String(), "[panic recovered]")
+	assert.Contains(t, buf.String(), "level=ERROR msg=[access] method=GET path=/panic status=500")
+}
+
+func TestCORS(t *testing.T) {
+
+	ws := webserver.NewWebServer("0")
+	ws.Use(webserver.CORS(webserver.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}))
+	ws.AddHandler("POST /zipcode/", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/zipcode/", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp := httptest.NewRecorder()
+	ws.Handler().ServeHTTP(resp, preflight)
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "https://app.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, resp.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+
+	req := httptest.NewRequest(http.MethodPost, "/zipcode/", strings.NewReader(`{}`))
+	req.Header.Set("Origin", "https://app.example.com")
+	resp = httptest.NewRecorder()
+	ws.Handler().ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "https://app.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+
+	preflight.Header.Set("Origin", "https://evil.example.com")
+	resp = httptest.NewRecorder()
+	ws.Handler().ServeHTTP(resp, preflight)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,99 @@
+package webserver
+
+import "time"
+
+const (
+	defaultReadTimeout       = 10 * time.Second
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 60 * time.Second
+	defaultMaxHeaderBytes    = 1 << 20
+	defaultMaxBodyBytes      = 1 << 20
+)
+
+type Option func(*WebServer)
+
+func WithReadTimeout(d time.Duration) Option {
+	return func(s *WebServer) {
+		s.readTimeout = d
+	}
+}
+
+// WithReadHeaderTimeout bounds how long a client may take to send the
+// headers, which is what protects against slowloris.
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(s *WebServer) {
+		s.readHeaderTimeout = d
+	}
+}
+
+// WithWriteTimeout must be longer than the slowest upstream chain the
+// handlers call, otherwise the response is cut.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(s *WebServer) {
+		s.writeTimeout = d
+	}
+}
+
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *WebServer) {
+		s.idleTimeout = d
+	}
+}
+
+func WithDrainTimeout(d time.Duration) Option {
+	return func(s *WebServer) {
+		s.DrainTimeout = d
+	}
+}
+
+func WithMaxHeaderBytes(n int) Option {
+	return func(s *WebServer) {
+		s.maxHeaderBytes = n
+	}
+}
+
+// WithMaxBodyBytes is the request body limit of the routes that do not set
+// their own with WithRouteMaxBodyBytes.
+func WithMaxBodyBytes(n int64) Option {
+	return func(s *WebServer) {
+		s.maxBodyBytes = n
+	}
+}
+
+// WithMaxConcurrentRequests answers 503 once n requests are in flight.
+// Zero means no limit.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(s *WebServer) {
+		s.maxConcurrentRequests = n
+	}
+}
+
+// WithBaggageFromHeaders maps the X-Tenant-Id and X-Client-App headers to
+// baggage before the server span starts, so the span gets them as well.
+func WithBaggageFromHeaders() Option {
+	return func(s *WebServer) {
+		s.baggageFromHeaders = true
+	}
+}
+
+type route struct {
+	maxBodyBytes int64
+	middlewares  []Middleware
+}
+
+type RouteOption func(*route)
+
+func WithRouteMaxBodyBytes(n int64) RouteOption {
+	return func(r *route) {
+		r.maxBodyBytes = n
+	}
+}
+
+// WithRouteMiddleware adds middlewares that only run for this route, inside
+// the ones added with Use.
+func WithRouteMiddleware(mws ...Middleware) RouteOption {
+	return func(r *route) {
+		r.middlewares = append(r.middlewares, mws...)
+	}
+}
@@ -3,67 +3,56 @@ package webserver
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/entity"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/usecase"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
 )
 
 func GetWeatherByZipcodeHandler(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
-	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
 
 	tracer := otel.Tracer("weatherByZipcode-tracer")
 
-	w.Header().Add("Content-Type", "application/json")
-
 	httpClient := http.DefaultClient
 
 	zipcodeDto, err := entity.NewZipcode(r.PathValue("zipcode"))
 	if err != nil {
-
-		code := http.StatusInternalServerError
-		if err.Error() == "zip code must be 8 numeric digits" {
-			code = http.StatusNotFound
-		}
-
-		w.WriteHeader(code)
-		json.NewEncoder(w).Encode(&dto.ErroDto{Msg: err.Error()})
-		w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
 
 	addressDto, err := usecase.NewAddressByZipcode(ctx, tracer, *zipcodeDto, httpClient)
 	if err != nil {
-
-		code := http.StatusInternalServerError
-		if err.Error() == "zip code not found" {
-			code = http.StatusNotFound
-		}
-
-		w.WriteHeader(code)
-		json.NewEncoder(w).Encode(&dto.ErroDto{Msg: err.Error()})
-		w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
 
 	weatherDto, err := usecase.NewWeatherByAddress(ctx, tracer, *addressDto, httpClient)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(&dto.ErroDto{Msg: err.Error()})
+		writeError(w, r, err)
 		return
 	}
 
-	localeWeatherDto, err := entity.NewLocaleWeather(addressDto.Localidade, weatherDto.Current.TempC)
+	localeWeatherDto, err := entity.NewLocaleWeather(addressDto.Localidade, weatherDto.TempC)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(&dto.ErroDto{Msg: err.Error()})
+		writeError(w, r, err)
 		return
 	}
 
+	localeWeatherDto.Location = &dto.LocationDto{
+		Name:     weatherDto.Location,
+		Region:   weatherDto.Region,
+		Lat:      weatherDto.Lat,
+		Lon:      weatherDto.Lon,
+		Provider: weatherDto.Provider,
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	writeCacheHeaders(w, time.Now(), addressDto.Cache, weatherDto.Cache)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(localeWeatherDto)
 }
@@ -1,36 +1,166 @@
 package webserver
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const defaultDrainTimeout = 10 * time.Second
+
 type WebServer struct {
 	WebServerPort string
 	Mux           *http.ServeMux
+	DrainTimeout  time.Duration
+
+	mu     sync.Mutex
+	server *http.Server
+
+	readTimeout           time.Duration
+	readHeaderTimeout     time.Duration
+	writeTimeout          time.Duration
+	idleTimeout           time.Duration
+	maxHeaderBytes        int
+	maxBodyBytes          int64
+	maxConcurrentRequests int
+	baggageFromHeaders    bool
+	middlewares           []Middleware
+
+	livenessChecks  []namedCheck
+	readinessChecks []namedCheck
+	draining        atomic.Bool
 }
 
-func NewWebServer(serverPort string) *WebServer {
+func NewWebServer(serverPort string, opts ...Option) *WebServer {
 	slog.Info("[webserver created]")
 
-	return &WebServer{
-		WebServerPort: serverPort,
-		Mux:           http.NewServeMux(),
+	s := &WebServer{
+		WebServerPort:     serverPort,
+		Mux:               http.NewServeMux(),
+		DrainTimeout:      defaultDrainTimeout,
+		readTimeout:       defaultReadTimeout,
+		readHeaderTimeout: defaultReadHeaderTimeout,
+		writeTimeout:      defaultWriteTimeout,
+		idleTimeout:       defaultIdleTimeout,
+		maxHeaderBytes:    defaultMaxHeaderBytes,
+		maxBodyBytes:      defaultMaxBodyBytes,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
-func (s *WebServer) AddHandler(path string, handler http.HandlerFunc) {
-	s.Mux.Handle(path, handler)
-	slog.Info("[route added]", "path", path)
+func (s *WebServer) AddHandler(path string, handler http.HandlerFunc, opts ...RouteOption) {
+
+	rt := &route{maxBodyBytes: s.maxBodyBytes}
+	for _, opt := range opts {
+		opt(rt)
+	}
+
+	s.Mux.Handle(path, limitBody(rt.maxBodyBytes, chain(handler, rt.middlewares...)))
+	slog.Info("[route added]", "path", path, "maxBodyBytes", rt.maxBodyBytes)
 }
 
-func (s *WebServer) Start() error {
-	slog.Info("[server listening]", "port", s.WebServerPort)
+// Use adds middlewares to every request, including the ones that match no
+// route. They run inside the request span, in the order they were added.
+func (s *WebServer) Use(mws ...Middleware) {
+	s.middlewares = append(s.middlewares, mws...)
+}
 
-	err := http.ListenAndServe(":"+s.WebServerPort, s.Mux)
+// Handler returns the Mux wrapped by the tracing and metrics instrumentation,
+// the middlewares added with Use and the concurrency limit. The health
+// endpoints are answered before all of them.
+func (s *WebServer) Handler() http.Handler {
+	return s.health(instrument(s.Mux, s.baggageFromHeaders, chain(limitConcurrency(s.maxConcurrentRequests, s.Mux), s.middlewares...)))
+}
+
+// Start listens on WebServerPort and serves until ctx is done, see Serve.
+func (s *WebServer) Start(ctx context.Context) error {
+
+	ln, err := net.Listen("tcp", ":"+s.WebServerPort)
 	if err != nil {
 		return err
 	}
 
+	return s.Serve(ctx, ln)
+}
+
+// Serve serves on ln until ctx is done and then drains the in-flight requests
+// for up to DrainTimeout, closing the connections left after it. It only
+// returns after the server has stopped, so the caller can flush the
+// telemetry right after it.
+func (s *WebServer) Serve(ctx context.Context, ln net.Listener) error {
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadTimeout:       s.readTimeout,
+		ReadHeaderTimeout: s.readHeaderTimeout,
+		WriteTimeout:      s.writeTimeout,
+		IdleTimeout:       s.idleTimeout,
+		MaxHeaderBytes:    s.maxHeaderBytes,
+	}
+
+	s.mu.Lock()
+	s.server = server
+	s.mu.Unlock()
+
+	chErr := make(chan error, 1)
+	go func() {
+		slog.Info("[server listening]", "addr", ln.Addr().String())
+		chErr <- server.Serve(ln)
+	}()
+
+	select {
+	case err := <-chErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("[server draining]", "timeout", s.DrainTimeout)
+	s.draining.Store(true)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.DrainTimeout)
+	defer cancel()
+
+	err := s.Shutdown(shutdownCtx)
+	if err != nil {
+		return err
+	}
+
+	err = <-chErr
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	slog.Info("[server stopped]")
 	return nil
 }
+
+// Shutdown stops accepting connections and waits for the in-flight requests
+// until ctx is done. Past that the connections left are closed, cancelling
+// the context of their requests, so stuck handlers do not outlive it.
+func (s *WebServer) Shutdown(ctx context.Context) error {
+
+	s.mu.Lock()
+	server := s.server
+	s.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		slog.Warn("[server drain timeout]", "error", err.Error())
+		server.Close()
+	}
+
+	return err
+}
@@ -1,7 +1,10 @@
 package webserver_test
 
 import (
+	"context"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -9,20 +12,152 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestWebServer(t *testing.T) {
+// serve starts ws on a free port and returns its base URL. The server stops
+// when ctx is done and Serve's result is sent to chErr.
+func serve(t *testing.T, ctx context.Context, ws *webserver.WebServer) (string, chan error) {
 
-	ws := webserver.NewWebServer("9080")
-	ws.AddHandler("GET /ping", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chErr := make(chan error, 1)
 	go func() {
-		err := ws.Start()
-		assert.Nil(t, err)
+		chErr <- ws.Serve(ctx, ln)
 	}()
 
+	return "http://" + ln.Addr().String(), chErr
+}
+
+// noKeepAlive does not leave idle connections behind for Shutdown to wait on.
+var noKeepAlive = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+func TestWebServer(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ws := webserver.NewWebServer("0")
+	ws.AddHandler("GET /ping", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	url, chErr := serve(t, ctx, ws)
+
 	//---
-	time.Sleep(30 * time.Millisecond)
-	req, err := http.NewRequest(http.MethodGet, "http://localhost:9080/ping", nil)
+	req, err := http.NewRequest(http.MethodGet, url+"/ping", nil)
 	assert.Nil(t, err)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := noKeepAlive.Do(req)
 	assert.Nil(t, err)
 	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	resp.Body.Close()
+
+	cancel()
+	assert.Nil(t, <-chErr)
+}
+
+func TestWebServerDrainsInFlightRequests(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	ws := webserver.NewWebServer("0")
+	ws.AddHandler("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	url, chErr := serve(t, ctx, ws)
+
+	chResp := make(chan *http.Response, 1)
+	go func() {
+		resp, err := noKeepAlive.Get(url + "/slow")
+		assert.Nil(t, err)
+		chResp <- resp
+	}()
+	<-started
+
+	cancel()
+	assert.Nil(t, <-chErr)
+
+	resp := <-chResp
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	_, err := noKeepAlive.Get(url + "/slow")
+	assert.Error(t, err)
+}
+
+func TestWebServerDrainTimeout(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	ws := webserver.NewWebServer("0")
+	ws.DrainTimeout = 50 * time.Millisecond
+	ws.AddHandler("GET /stuck", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		close(cancelled)
+	})
+
+	url, chErr := serve(t, ctx, ws)
+
+	go noKeepAlive.Get(url + "/stuck")
+	<-started
+
+	cancel()
+	assert.ErrorIs(t, <-chErr, context.DeadlineExceeded)
+
+	// the connection is closed past the deadline, so the stuck handler stops
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stuck handler kept running after the drain timeout")
+	}
+}
+
+func TestWebServerNotReadyWhileDraining(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ws := webserver.NewWebServer("0")
+	ws.DrainTimeout = 5 * time.Second
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ws.AddHandler("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	url, done := serve(t, ctx, ws)
+
+	resp, err := noKeepAlive.Get(url + "/readyz")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	go noKeepAlive.Get(url + "/slow")
+	<-started
+	cancel()
+
+	// draining is flagged right after ctx is done, while /slow holds the server
+	assert.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		ws.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code == http.StatusServiceUnavailable && rec.Body.String() == "{\"status\":\"draining\"}\n"
+	}, time.Second, 10*time.Millisecond)
+
+	close(release)
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(15 * time.Second):
+		t.Fatal("server did not stop after the in-flight request finished")
+	}
 }
@@ -2,32 +2,37 @@ package webserver
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
-	"strings"
 
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/domainerr"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/entity"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/usecase"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
 )
 
 func GetZipcodeHandler(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
-	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
 
 	trc := otel.Tracer("weatherByZipcode-tracer")
 
-	slog.Debug("[struct]", "r.Body", r.Body)
+	slog.DebugContext(ctx, "[struct]", "r.Body", r.Body)
 
 	var z dto.ZipcodeBodyDto
 
 	err := json.NewDecoder(r.Body).Decode(&z)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(&dto.ErroDto{Msg: err.Error()})
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeLimitError(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+
+		// Malformed JSON and a cep that is not a string are invalid input too.
+		writeError(w, r, domainerr.Wrap(domainerr.ErrInvalidZipcode, 0, err))
 		return
 	}
 
@@ -35,41 +40,23 @@ func GetZipcodeHandler(w http.ResponseWriter, r *http.Request) {
 
 	httpClient := http.DefaultClient
 
-	slog.Debug("[struct]", "z.Cep", z.Cep)
+	slog.DebugContext(ctx, "[struct]", "z.Cep", z.Cep)
 
 	zipcodeDto, err := entity.NewZipcode(z.Cep)
 	if err != nil {
-		stsCod := http.StatusInternalServerError
-		stsMsg := err.Error()
-
-		if strings.Contains(strings.ToLower(err.Error()), "invalid zipcode") {
-			stsCod = http.StatusNotFound
-			stsMsg = "invalid zipcode"
-		}
-
-		w.WriteHeader(stsCod)
-		json.NewEncoder(w).Encode(&dto.ErroDto{Msg: stsMsg})
+		writeError(w, r, err)
 		return
 	}
 
-	slog.Debug("[struct]", "zipcodeDto", zipcodeDto)
+	slog.DebugContext(ctx, "[struct]", "zipcodeDto", zipcodeDto)
 
 	localeWeatherDto, err := usecase.NewWeatherByServiceB(ctx, trc, httpClient, *zipcodeDto)
 	if err != nil {
-		stsCod := http.StatusInternalServerError
-		stsMsg := err.Error()
-
-		if strings.Contains(strings.ToLower(err.Error()), "not found") {
-			stsCod = http.StatusNotFound
-			stsMsg = "can not find zipcode"
-		}
-
-		w.WriteHeader(stsCod)
-		json.NewEncoder(w).Encode(&dto.ErroDto{Msg: stsMsg})
+		writeError(w, r, err)
 		return
 	}
 
-	slog.Debug("[struct]", "localeWeatherDto", localeWeatherDto)
+	slog.DebugContext(ctx, "[struct]", "localeWeatherDto", localeWeatherDto)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(localeWeatherDto)
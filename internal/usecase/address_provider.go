@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/domainerr"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
+)
+
+const (
+	AddressProviderViaCep     = "viacep"
+	AddressProviderBrasilApi  = "brasilapi"
+	AddressProviderAwesomeApi = "awesomeapi"
+
+	// bounds each attempt, so a slow provider still leaves time for the fallbacks
+	defaultAddressProviderTimeout = 3 * time.Second
+)
+
+// AddressProvider resolves a zip code into an address. It returns
+// domainerr.ErrZipcodeNotFound when the provider is sure the zip code does not exist,
+// any other error makes NewAddressByZipcode try the next provider.
+type AddressProvider interface {
+	Name() string
+	Address(ctx context.Context, client *http.Client, z dto.ZipcodeDto) (*dto.AddressDto, error)
+}
+
+var addressProviders = map[string]AddressProvider{
+	AddressProviderViaCep:     viaCep{},
+	AddressProviderBrasilApi:  brasilApi{},
+	AddressProviderAwesomeApi: awesomeApi{},
+}
+
+// AddressProviders returns the providers in the order set by ADDRESS_PROVIDERS
+// (e.g. "brasilapi,viacep"), skipping unknown names. Defaults to ViaCEP,
+// BrasilAPI and AwesomeAPI.
+func AddressProviders() []AddressProvider {
+
+	names := []string{AddressProviderViaCep, AddressProviderBrasilApi, AddressProviderAwesomeApi}
+	if v := os.Getenv("ADDRESS_PROVIDERS"); v != "" {
+		names = strings.Split(v, ",")
+	}
+
+	var providers []AddressProvider
+	for _, name := range names {
+		p, ok := addressProviders[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			slog.Warn("[unknown address provider]", "name", name)
+			continue
+		}
+		providers = append(providers, p)
+	}
+
+	return providers
+}
+
+type viaCep struct{}
+
+func (viaCep) Name() string { return AddressProviderViaCep }
+
+func (viaCep) Address(ctx context.Context, client *http.Client, z dto.ZipcodeDto) (*dto.AddressDto, error) {
+
+	var a dto.AddressDto
+	if err := getJson(ctx, client, "https://viacep.com.br/ws/"+z.Zipcode+"/json/", nil, &a); err != nil {
+		return nil, err
+	}
+
+	if a.Error != "" {
+		return nil, domainerr.ErrZipcodeNotFound
+	}
+
+	return &a, nil
+}
+
+type brasilApi struct{}
+
+func (brasilApi) Name() string { return AddressProviderBrasilApi }
+
+func (brasilApi) Address(ctx context.Context, client *http.Client, z dto.ZipcodeDto) (*dto.AddressDto, error) {
+
+	var b dto.BrasilApiCepDto
+	if err := getJson(ctx, client, "https://brasilapi.com.br/api/cep/v2/"+z.Zipcode, nil, &b); err != nil {
+		return nil, notFound(err)
+	}
+
+	return &dto.AddressDto{
+		Cep:        b.Cep,
+		Localidade: b.City,
+		Uf:         b.State,
+		Lat:        parseCoordinate(b.Location.Coordinates.Latitude),
+		Lon:        parseCoordinate(b.Location.Coordinates.Longitude),
+	}, nil
+}
+
+type awesomeApi struct{}
+
+func (awesomeApi) Name() string { return AddressProviderAwesomeApi }
+
+func (awesomeApi) Address(ctx context.Context, client *http.Client, z dto.ZipcodeDto) (*dto.AddressDto, error) {
+
+	var a dto.AwesomeApiCepDto
+	if err := getJson(ctx, client, "https://cep.awesomeapi.com.br/json/"+z.Zipcode, nil, &a); err != nil {
+		return nil, notFound(err)
+	}
+
+	return &dto.AddressDto{
+		Cep:        a.Cep,
+		Localidade: a.City,
+		Uf:         a.State,
+		Lat:        parseCoordinate(a.Lat),
+		Lon:        parseCoordinate(a.Lng),
+	}, nil
+}
+
+// notFound maps a 404 from the providers that signal unknown zip codes with
+// the status code to domainerr.ErrZipcodeNotFound.
+func notFound(err error) error {
+	var stsErr *webclient.StatusError
+	if errors.As(err, &stsErr) && stsErr.StatusCode == http.StatusNotFound {
+		return domainerr.Wrap(domainerr.ErrZipcodeNotFound, stsErr.StatusCode, stsErr)
+	}
+	return err
+}
+
+func getJson(ctx context.Context, client *http.Client, url string, query map[string]string, v any) error {
+
+	wcReq, err := webclient.NewWebclient(ctx, client, http.MethodGet, url, query)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	return upstreamError(wcReq.Do(func(p []byte) error {
+		err := json.Unmarshal(p, v)
+		if err != nil {
+			slog.ErrorContext(ctx, "[body unmarshal]", "error", err.Error())
+		}
+		return err
+	}))
+}
@@ -0,0 +1,292 @@
+package usecase
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/usecase"
+
+	cacheTypeAddress = "address"
+	cacheTypeWeather = "weather"
+
+	defaultCacheSize       = 1024
+	defaultAddressCacheTTL = 24 * time.Hour
+	defaultWeatherCacheTTL = 5 * time.Minute
+)
+
+// Cache stores the upstream answers. Implementations log their own failures
+// and report them as misses, a broken cache must never fail a request.
+type Cache interface {
+	Get(ctx context.Context, key string) (value []byte, info dto.CacheDto, ok bool)
+	Set(ctx context.Context, key string, value []byte, info dto.CacheDto)
+}
+
+var (
+	cacheMu sync.RWMutex
+	cache   Cache = NewMemoryCache(defaultCacheSize)
+)
+
+// SetCache replaces the in-memory cache used by the lookups, e.g. with a
+// RedisCache shared by the replicas. nil disables caching.
+func SetCache(c Cache) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache = c
+}
+
+func currentCache() Cache {
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	return cache
+}
+
+// durationFromEnv reads a duration such as "5m" from name, falling back to def
+// when it is unset or invalid.
+func durationFromEnv(name string, def time.Duration) time.Duration {
+
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		slog.Warn("[invalid duration]", "name", name, "value", v)
+		return def
+	}
+
+	return d
+}
+
+// cached serves key from the cache, or calls load and stores its result for
+// ttl. Concurrent misses for the same key share a single load. The outcome is
+// recorded on span and on the cache.requests counter.
+func cached[T any](ctx context.Context, span trace.Span, cacheType string, key string, ttl time.Duration, load func(context.Context) (*T, error)) (*T, dto.CacheDto, error) {
+
+	key = cacheType + ":" + key
+
+	c := currentCache()
+	if c == nil {
+		v, err := coalesce(ctx, span, key, load)
+		return v, dto.CacheDto{}, err
+	}
+
+	if p, info, ok := c.Get(ctx, key); ok {
+		var v T
+		if err := json.Unmarshal(p, &v); err == nil {
+			recordCache(ctx, span, cacheType, true)
+			info.Hit = true
+			return &v, info, nil
+		}
+		slog.WarnContext(ctx, "[cache unmarshal]", "key", key)
+	}
+
+	recordCache(ctx, span, cacheType, false)
+
+	// only the caller that makes the upstream calls stores the result, the
+	// ones waiting on it get the same entry
+	e, err := coalesce(ctx, span, key, func(ctx context.Context) (*cacheEntry[T], error) {
+		v, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		info := dto.CacheDto{StoredAt: now, ExpiresAt: now.Add(ttl)}
+
+		if p, err := json.Marshal(v); err == nil {
+			c.Set(ctx, key, p, info)
+		}
+
+		return &cacheEntry[T]{value: v, info: info}, nil
+	})
+	if err != nil {
+		return nil, dto.CacheDto{}, err
+	}
+
+	v := *e.value
+	return &v, e.info, nil
+}
+
+type cacheEntry[T any] struct {
+	value *T
+	info  dto.CacheDto
+}
+
+var cacheRequests = sync.OnceValue(func() metric.Int64Counter {
+	counter, err := otel.Meter(instrumentationName).Int64Counter(
+		"cache.requests",
+		metric.WithDescription("Lookups served from the cache (hit) or from upstream (miss)."),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return counter
+})
+
+func recordCache(ctx context.Context, span trace.Span, cacheType string, hit bool) {
+
+	span.SetAttributes(
+		attribute.String("cache.type", cacheType),
+		attribute.Bool("cache.hit", hit),
+	)
+
+	cacheRequests().Add(ctx, 1, metric.WithAttributes(
+		attribute.String("cache.type", cacheType),
+		attribute.Bool("cache.hit", hit),
+	))
+}
+
+type memoryEntry struct {
+	key   string
+	value []byte
+	info  dto.CacheDto
+}
+
+// MemoryCache is an LRU cache, entries are dropped when they expire or when
+// size is reached.
+type MemoryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, dto.CacheDto, bool) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, dto.CacheDto{}, false
+	}
+
+	e := el.Value.(*memoryEntry)
+	if time.Now().After(e.info.ExpiresAt) {
+		m.order.Remove(el)
+		delete(m.entries, key)
+		return nil, dto.CacheDto{}, false
+	}
+
+	m.order.MoveToFront(el)
+	return e.value, e.info, true
+}
+
+func (m *MemoryCache) Set(ctx context.Context, key string, value []byte, info dto.CacheDto) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.entries[key]; ok {
+		el.Value = &memoryEntry{key: key, value: value, info: info}
+		m.order.MoveToFront(el)
+		return
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, value: value, info: info})
+
+	for m.order.Len() > m.size {
+		el := m.order.Back()
+		m.order.Remove(el)
+		delete(m.entries, el.Value.(*memoryEntry).key)
+	}
+}
+
+// RedisCache shares the cache between replicas. Entries expire in Redis with
+// the same TTL the lookups set.
+type RedisCache struct {
+	client redis.Cmdable
+}
+
+func NewRedisCache(client redis.Cmdable) *RedisCache {
+	return &RedisCache{client: client}
+}
+
+type redisEntry struct {
+	Value    json.RawMessage `json:"value"`
+	StoredAt time.Time       `json:"stored_at"`
+}
+
+func (r *RedisCache) Get(ctx context.Context, key string) ([]byte, dto.CacheDto, bool) {
+
+	p, err := r.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			slog.WarnContext(ctx, "[redis get]", "key", key, "error", err.Error())
+		}
+		return nil, dto.CacheDto{}, false
+	}
+
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil || ttl <= 0 {
+		return nil, dto.CacheDto{}, false
+	}
+
+	var e redisEntry
+	if err := json.Unmarshal(p, &e); err != nil {
+		slog.WarnContext(ctx, "[redis unmarshal]", "key", key, "error", err.Error())
+		return nil, dto.CacheDto{}, false
+	}
+
+	return e.Value, dto.CacheDto{StoredAt: e.StoredAt, ExpiresAt: time.Now().Add(ttl)}, true
+}
+
+func (r *RedisCache) Set(ctx context.Context, key string, value []byte, info dto.CacheDto) {
+
+	p, err := json.Marshal(redisEntry{Value: value, StoredAt: info.StoredAt})
+	if err != nil {
+		return
+	}
+
+	if err := r.client.Set(ctx, key, p, time.Until(info.ExpiresAt)).Err(); err != nil {
+		slog.WarnContext(ctx, "[redis set]", "key", key, "error", err.Error())
+	}
+}
+
+// CacheFromEnv returns a RedisCache when REDIS_ADDR is set and an in-memory
+// cache of CACHE_SIZE entries otherwise.
+func CacheFromEnv() Cache {
+
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		return NewRedisCache(redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: os.Getenv("REDIS_PASSWORD"),
+		}))
+	}
+
+	size := defaultCacheSize
+	if v := os.Getenv("CACHE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			slog.Warn("[invalid CACHE_SIZE]", "value", v)
+		} else {
+			size = n
+		}
+	}
+
+	return NewMemoryCache(size)
+}
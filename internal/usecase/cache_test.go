@@ -0,0 +1,242 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/entity"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/mockup"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/usecase"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMemoryCache(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Now()
+	fresh := dto.CacheDto{StoredAt: now, ExpiresAt: now.Add(time.Minute)}
+
+	c := usecase.NewMemoryCache(2)
+	c.Set(ctx, "a", []byte("1"), fresh)
+	c.Set(ctx, "b", []byte("2"), fresh)
+
+	// "a" becomes the most recently used, so "b" is evicted by "c"
+	_, _, ok := c.Get(ctx, "a")
+	assert.True(t, ok)
+	c.Set(ctx, "c", []byte("3"), fresh)
+
+	_, _, ok = c.Get(ctx, "b")
+	assert.False(t, ok)
+
+	v, info, ok := c.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), v)
+	assert.Equal(t, fresh, info)
+
+	c.Set(ctx, "expired", []byte("4"), dto.CacheDto{StoredAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)})
+	_, _, ok = c.Get(ctx, "expired")
+	assert.False(t, ok)
+}
+
+func TestNewAddressByZipcodeCache(t *testing.T) {
+
+	usecase.SetCache(usecase.NewMemoryCache(16))
+
+	mockRoundTripper := new(mockup.MockRoundTripper)
+	mockClient := &http.Client{Transport: mockRoundTripper}
+
+	mockRoundTripper.On("RoundTrip", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader([]byte(`{"cep":"01001-000","localidade":"São Paulo","uf":"SP"}`))),
+	}, nil).Once()
+
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+
+	zipcodeDto, err := entity.NewZipcode("01001000")
+	assert.Nil(t, err)
+
+	type Lote struct {
+		Hit bool
+	}
+
+	table := []Lote{{Hit: false}, {Hit: true}}
+
+	for _, item := range table {
+		addressDto, err := usecase.NewAddressByZipcode(context.Background(), tracer, *zipcodeDto, mockClient)
+		assert.Nil(t, err)
+		assert.Equal(t, "São Paulo", addressDto.Localidade)
+		assert.Equal(t, item.Hit, addressDto.Cache.Hit)
+		assert.WithinDuration(t, time.Now().Add(24*time.Hour), addressDto.Cache.ExpiresAt, time.Minute)
+	}
+
+	mockRoundTripper.AssertNumberOfCalls(t, "RoundTrip", 1)
+
+	var hits []bool
+	for _, s := range rec.Ended() {
+		if s.Name() == "NewAddressByZipcode" {
+			attrs := attribute.NewSet(s.Attributes()...)
+			hit, _ := attrs.Value("cache.hit")
+			hits = append(hits, hit.AsBool())
+		}
+	}
+	assert.Equal(t, []bool{false, true}, hits)
+}
+
+// fakeRedis keeps the entries in a map and counts the writes. The commands
+// the cache does not use panic through the nil embedded Cmdable.
+type fakeRedis struct {
+	redis.Cmdable
+	mu      sync.Mutex
+	entries map[string]string
+	expires map[string]time.Time
+	sets    int
+	err     error
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{entries: map[string]string{}, expires: map[string]time.Time{}}
+}
+
+func (f *fakeRedis) Get(ctx context.Context, key string) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return redis.NewStringResult("", f.err)
+	}
+	v, ok := f.entries[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func (f *fakeRedis) PTTL(ctx context.Context, key string) *redis.DurationCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	exp, ok := f.expires[key]
+	if !ok {
+		return redis.NewDurationResult(-2, nil)
+	}
+	return redis.NewDurationResult(time.Until(exp), nil)
+}
+
+func (f *fakeRedis) Set(ctx context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sets++
+	if f.err != nil {
+		return redis.NewStatusResult("", f.err)
+	}
+	f.entries[key] = string(value.([]byte))
+	f.expires[key] = time.Now().Add(expiration)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func TestRedisCache(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	info := dto.CacheDto{StoredAt: now, ExpiresAt: now.Add(time.Minute)}
+
+	fake := newFakeRedis()
+	c := usecase.NewRedisCache(fake)
+
+	c.Set(ctx, "weather:São Paulo,SP,Brazil", []byte(`{"temp_c":20}`), info)
+
+	v, got, ok := c.Get(ctx, "weather:São Paulo,SP,Brazil")
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"temp_c":20}`, string(v))
+	assert.True(t, now.Equal(got.StoredAt))
+	assert.WithinDuration(t, info.ExpiresAt, got.ExpiresAt, time.Second)
+
+	type Lote struct {
+		Name  string
+		Key   string
+		Setup func(f *fakeRedis)
+	}
+
+	// every failure is a miss, never an error
+	table := []Lote{
+		{Name: "missing", Key: "nope"},
+		{Name: "expired", Key: "old", Setup: func(f *fakeRedis) {
+			f.entries["old"] = `{"value":{},"stored_at":"2024-01-01T00:00:00Z"}`
+			f.expires["old"] = time.Now().Add(-time.Second)
+		}},
+		{Name: "corrupt", Key: "bad", Setup: func(f *fakeRedis) {
+			f.entries["bad"] = `not json`
+			f.expires["bad"] = time.Now().Add(time.Minute)
+		}},
+		{Name: "redis down", Key: "weather:São Paulo,SP,Brazil", Setup: func(f *fakeRedis) { f.err = errors.New("connection refused") }},
+	}
+
+	for _, item := range table {
+		t.Run(item.Name, func(t *testing.T) {
+			if item.Setup != nil {
+				item.Setup(fake)
+			}
+			_, _, ok := c.Get(ctx, item.Key)
+			assert.False(t, ok)
+		})
+	}
+
+	// a failed write is only logged
+	c.Set(ctx, "weather:Rio Branco,AC,Brazil", []byte(`{}`), info)
+}
+
+func TestCachedCoalescedStoresOnce(t *testing.T) {
+
+	fake := newFakeRedis()
+	usecase.SetCache(usecase.NewRedisCache(fake))
+	defer usecase.SetCache(usecase.NewMemoryCache(16))
+
+	rt := &blockingRoundTripper{started: make(chan struct{}), release: make(chan struct{})}
+	client := &http.Client{Transport: rt}
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+
+	zipcodeDto, err := entity.NewZipcode("01001000")
+	assert.Nil(t, err)
+
+	lookup := func() {
+		addressDto, err := usecase.NewAddressByZipcode(context.Background(), tracer, *zipcodeDto, client)
+		assert.Nil(t, err)
+		assert.False(t, addressDto.Cache.ExpiresAt.IsZero())
+	}
+
+	joins := make(chan string, 16)
+	usecase.SetCoalesceJoined(func(key string) { joins <- key })
+	defer usecase.SetCoalesceJoined(nil)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { defer wg.Done(); lookup() }()
+	<-rt.started
+
+	const waiters = 4
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); lookup() }()
+	}
+
+	// the leader and every waiter are on the flight before it lands
+	for i := 0; i < waiters+1; i++ {
+		<-joins
+	}
+	close(rt.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), rt.calls.Load())
+	assert.Equal(t, 1, fake.sets)
+}
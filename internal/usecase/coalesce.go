@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+)
+
+var flights singleflight.Group
+
+// joined, when set, is called once a caller waits on the flight of key.
+var joined func(key string)
+
+type flight struct {
+	value  any
+	leader trace.SpanContext
+}
+
+// coalesce runs a single load per key at a time: concurrent callers wait for
+// the one already in flight and share its result. Their spans are linked to
+// the span of the request that made the upstream calls.
+//
+// The load runs detached from the leader's cancellation and deadline, so a
+// client that hangs up does not fail the requests waiting on it. Loads must
+// bound themselves, as the provider attempts do with their timeouts. Every
+// caller, the leader included, stops waiting when its own ctx is done.
+func coalesce[T any](ctx context.Context, span trace.Span, key string, load func(context.Context) (*T, error)) (*T, error) {
+
+	ch := flights.DoChan(key, func() (any, error) {
+		value, err := load(context.WithoutCancel(ctx))
+		return flight{value: value, leader: span.SpanContext()}, err
+	})
+
+	if joined != nil {
+		joined(key)
+	}
+
+	var res singleflight.Result
+	select {
+	case res = <-ch:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	f := res.Val.(flight)
+	if res.Shared && !f.leader.Equal(span.SpanContext()) {
+		span.AddLink(trace.Link{SpanContext: f.leader, Attributes: []attribute.KeyValue{attribute.String("link.type", "coalesced")}})
+		span.SetAttributes(attribute.Bool("coalesced", true))
+	}
+
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	// every caller gets its own copy, since they set the cache info on it
+	value := *f.value.(*T)
+	return &value, nil
+}
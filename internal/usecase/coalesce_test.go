@@ -0,0 +1,181 @@
+package usecase_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/entity"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// blockingRoundTripper holds every request until release is closed.
+type blockingRoundTripper struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if b.calls.Add(1) == 1 {
+		close(b.started)
+	}
+	<-b.release
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(`{"cep":"01001-000","localidade":"São Paulo","uf":"SP"}`)),
+	}, nil
+}
+
+// hungRoundTripper never answers, like an upstream that accepted the
+// connection and went silent.
+type hungRoundTripper struct {
+	calls atomic.Int32
+}
+
+func (h *hungRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	h.calls.Add(1)
+	<-r.Context().Done()
+	return nil, r.Context().Err()
+}
+
+func TestNewWeatherByAddressCoalescedHungUpstream(t *testing.T) {
+
+	usecase.SetCache(usecase.NewMemoryCache(16))
+	t.Setenv("WEATHER_PROVIDERS", usecase.WeatherProviderOpenMeteo)
+	t.Setenv("WEATHER_PROVIDER_TIMEOUT", "100ms")
+
+	rt := &hungRoundTripper{}
+	client := &http.Client{Transport: rt}
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+
+	lat, lon := -23.5475, -46.63611
+	a := dto.AddressDto{Cep: "01001-000", Localidade: "São Paulo", Uf: "SP", Lat: &lat, Lon: &lon}
+
+	// the leader's client hangs up right away, the waiters keep waiting
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan error, 4)
+	go func() {
+		_, err := usecase.NewWeatherByAddress(leaderCtx, tracer, a, client)
+		done <- err
+	}()
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := usecase.NewWeatherByAddress(context.Background(), tracer, a, client)
+			done <- err
+		}()
+	}
+
+	for i := 0; i < 4; i++ {
+		select {
+		case err := <-done:
+			assert.NotNil(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("lookup blocked on the hung upstream")
+		}
+	}
+	assert.NotZero(t, rt.calls.Load())
+}
+
+func TestNewAddressByZipcodeCoalesced(t *testing.T) {
+
+	usecase.SetCache(usecase.NewMemoryCache(16))
+
+	rt := &blockingRoundTripper{started: make(chan struct{}), release: make(chan struct{})}
+	client := &http.Client{Transport: rt}
+
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+
+	zipcodeDto, err := entity.NewZipcode("01001000")
+	assert.Nil(t, err)
+
+	lookup := func() {
+		addressDto, err := usecase.NewAddressByZipcode(context.Background(), tracer, *zipcodeDto, client)
+		assert.Nil(t, err)
+		assert.Equal(t, "São Paulo", addressDto.Localidade)
+	}
+
+	joins := make(chan string, 16)
+	usecase.SetCoalesceJoined(func(key string) { joins <- key })
+	defer usecase.SetCoalesceJoined(nil)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { defer wg.Done(); lookup() }()
+	<-rt.started
+
+	const waiters = 4
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); lookup() }()
+	}
+
+	// the leader and every waiter are on the flight before it lands
+	for i := 0; i < waiters+1; i++ {
+		<-joins
+	}
+	close(rt.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), rt.calls.Load())
+
+	var leader []sdktrace.ReadOnlySpan
+	var linked []sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		if s.Name() != "NewAddressByZipcode" {
+			continue
+		}
+		if len(s.Links()) == 0 {
+			leader = append(leader, s)
+		} else {
+			linked = append(linked, s)
+		}
+	}
+
+	assert.Len(t, leader, 1)
+	assert.Len(t, linked, waiters)
+	for _, s := range linked {
+		assert.Equal(t, leader[0].SpanContext(), s.Links()[0].SpanContext)
+	}
+}
+
+func TestNewAddressByZipcodeCoalescedWaiterGone(t *testing.T) {
+
+	usecase.SetCache(usecase.NewMemoryCache(16))
+
+	rt := &blockingRoundTripper{started: make(chan struct{}), release: make(chan struct{})}
+	client := &http.Client{Transport: rt}
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+
+	zipcodeDto, err := entity.NewZipcode("01001000")
+	assert.Nil(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := usecase.NewAddressByZipcode(context.Background(), tracer, *zipcodeDto, client)
+		done <- err
+	}()
+	<-rt.started
+
+	// a waiter whose client hung up returns without waiting for the leader
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = usecase.NewAddressByZipcode(ctx, tracer, *zipcodeDto, client)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(rt.release)
+	assert.Nil(t, <-done)
+	assert.Equal(t, int32(1), rt.calls.Load())
+}
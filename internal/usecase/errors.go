@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/domainerr"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
+)
+
+// upstreamError classifies an error of a webclient call: throttling as
+// ErrUpstreamRateLimited and any other failure as ErrUpstreamUnavailable,
+// keeping the upstream status code. Cancellations are left as they are.
+func upstreamError(err error) error {
+
+	var derr *domainerr.Error
+	if err == nil || errors.As(err, &derr) || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	kind := domainerr.ErrUpstreamUnavailable
+	status := 0
+
+	var stsErr *webclient.StatusError
+	if errors.As(err, &stsErr) {
+		status = stsErr.StatusCode
+		if status == http.StatusTooManyRequests {
+			kind = domainerr.ErrUpstreamRateLimited
+		}
+	}
+
+	return domainerr.Wrap(kind, status, err)
+}
+
+// upstreamStatus is the status code carried by err, 0 when there is none.
+func upstreamStatus(err error) int {
+	var stsErr *webclient.StatusError
+	if errors.As(err, &stsErr) {
+		return stsErr.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,7 @@
+package usecase
+
+// SetCoalesceJoined makes coalesce call f once each caller waits on a flight,
+// so tests know every caller joined before releasing the upstream.
+func SetCoalesceJoined(f func(key string)) {
+	joined = f
+}
@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
+)
+
+// healthZipcode is a stable CEP (Praça da Sé) used to probe ViaCEP.
+const healthZipcode = "01001000"
+
+// CheckServiceB reports whether service-b answers its liveness probe.
+func CheckServiceB(ctx context.Context, cli *http.Client) error {
+	return ping(ctx, cli, "http://"+os.Getenv("SERVICE_B_HOST")+":"+os.Getenv("SERVICE_B_PORT")+"/healthz", nil)
+}
+
+func CheckWeatherApiKey(ctx context.Context) error {
+	if os.Getenv("WEATHER_API_KEY") == "" {
+		return errors.New("WEATHER_API_KEY is not set")
+	}
+	return nil
+}
+
+func CheckViaCep(ctx context.Context, cli *http.Client) error {
+	return ping(ctx, cli, "https://viacep.com.br/ws/"+healthZipcode+"/json/", nil)
+}
+
+func CheckBrasilApi(ctx context.Context, cli *http.Client) error {
+	return ping(ctx, cli, "https://brasilapi.com.br/api/cep/v2/"+healthZipcode, nil)
+}
+
+func CheckAwesomeApi(ctx context.Context, cli *http.Client) error {
+	return ping(ctx, cli, "https://cep.awesomeapi.com.br/json/"+healthZipcode, nil)
+}
+
+// CheckWeatherApi also validates the key, since WeatherAPI rejects unknown
+// keys with a non 200 status.
+func CheckWeatherApi(ctx context.Context, cli *http.Client) error {
+	return ping(ctx, cli, "https://api.weatherapi.com/v1/current.json", map[string]string{
+		"key": os.Getenv("WEATHER_API_KEY"),
+		"q":   "Sao Paulo",
+		"aqi": "no",
+	})
+}
+
+func CheckOpenMeteo(ctx context.Context, cli *http.Client) error {
+	return ping(ctx, cli, "https://api.open-meteo.com/v1/forecast", map[string]string{
+		"latitude":  "-23.55",
+		"longitude": "-46.63",
+		"current":   "temperature_2m",
+	})
+}
+
+type providerCheck func(ctx context.Context, cli *http.Client) error
+
+var addressChecks = map[string]providerCheck{
+	AddressProviderViaCep:     CheckViaCep,
+	AddressProviderBrasilApi:  CheckBrasilApi,
+	AddressProviderAwesomeApi: CheckAwesomeApi,
+}
+
+// CheckAddressProviders passes when at least one of the AddressProviders
+// answers, since the lookup falls back between them.
+func CheckAddressProviders(ctx context.Context, cli *http.Client) error {
+
+	var names []string
+	for _, p := range AddressProviders() {
+		names = append(names, p.Name())
+	}
+
+	return checkAny(ctx, cli, "address", names, func(name string) providerCheck { return addressChecks[name] })
+}
+
+// CheckWeatherProviders passes when at least one of the WeatherProviders is
+// usable, since the lookup falls back between them. Only their configuration
+// is checked, unless probe also asks for the providers to be pinged.
+func CheckWeatherProviders(ctx context.Context, cli *http.Client, probe bool) error {
+
+	var names []string
+	for _, p := range WeatherProviders() {
+		names = append(names, p.Name())
+	}
+
+	return checkAny(ctx, cli, "weather", names, func(name string) providerCheck {
+		return func(ctx context.Context, cli *http.Client) error {
+			if name == WeatherProviderWeatherApi {
+				if err := CheckWeatherApiKey(ctx); err != nil {
+					return err
+				}
+			}
+			if !probe {
+				return nil
+			}
+			switch name {
+			case WeatherProviderWeatherApi:
+				return CheckWeatherApi(ctx, cli)
+			case WeatherProviderOpenMeteo:
+				return CheckOpenMeteo(ctx, cli)
+			}
+			return nil
+		}
+	})
+}
+
+// checkAny runs the check of every provider at once and passes as soon as
+// one of them does.
+func checkAny(ctx context.Context, cli *http.Client, kind string, names []string, check func(name string) providerCheck) error {
+
+	if len(names) == 0 {
+		return fmt.Errorf("no %s provider configured", kind)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan error, len(names))
+	for _, name := range names {
+		go func() {
+			if err := check(name)(ctx, cli); err != nil {
+				results <- fmt.Errorf("%s: %w", name, err)
+				return
+			}
+			results <- nil
+		}()
+	}
+
+	var errs []error
+	for range names {
+		err := <-results
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// UpstreamChecksEnabled tells whether READINESS_CHECK_UPSTREAMS asks for the
+// third-party providers to be probed. They are off by default, since every
+// probe spends the providers' quota.
+func UpstreamChecksEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("READINESS_CHECK_UPSTREAMS"))
+	return enabled
+}
+
+// ping makes a single attempt that stays out of the circuit breakers, so
+// failed probes neither multiply nor open the circuit for real traffic.
+func ping(ctx context.Context, cli *http.Client, url string, query map[string]string) error {
+
+	wcReq, err := webclient.NewWebclient(ctx, cli, http.MethodGet, url, query,
+		webclient.WithRetryPolicy(webclient.NoRetry),
+		webclient.WithBreakers(nil),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create health request: %w", err)
+	}
+
+	return wcReq.Do(func([]byte) error { return nil })
+}
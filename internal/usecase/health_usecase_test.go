@@ -0,0 +1,108 @@
+package usecase_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/mockup"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckUpstreamSingleAttempt(t *testing.T) {
+
+	webclient.DefaultBreakers.Reset()
+
+	mockRoundTripper := new(mockup.MockRoundTripper)
+	mockRoundTripper.On("RoundTrip", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}, nil)
+	mockClient := &http.Client{Transport: mockRoundTripper}
+
+	// more failed probes than it takes to open a breaker
+	const probes = 2 * 20
+	for i := 0; i < probes; i++ {
+		assert.Error(t, usecase.CheckOpenMeteo(context.Background(), mockClient))
+	}
+	mockRoundTripper.AssertNumberOfCalls(t, "RoundTrip", probes)
+
+	// real traffic still reaches the upstream
+	wc, err := webclient.NewWebclient(context.Background(), mockClient, http.MethodGet, "https://api.open-meteo.com/v1/forecast", nil, webclient.WithRetryPolicy(webclient.NoRetry))
+	assert.Nil(t, err)
+	assert.NotErrorIs(t, wc.Do(func([]byte) error { return nil }), webclient.ErrCircuitOpen)
+	mockRoundTripper.AssertNumberOfCalls(t, "RoundTrip", probes+1)
+}
+
+func TestUpstreamChecksEnabled(t *testing.T) {
+
+	type Lote struct {
+		Value   string
+		Enabled bool
+	}
+
+	table := []Lote{
+		{Value: "", Enabled: false},
+		{Value: "false", Enabled: false},
+		{Value: "true", Enabled: true},
+		{Value: "1", Enabled: true},
+	}
+
+	for _, item := range table {
+		t.Setenv("READINESS_CHECK_UPSTREAMS", item.Value)
+		assert.Equal(t, item.Enabled, usecase.UpstreamChecksEnabled(), item.Value)
+	}
+}
+
+func TestCheckProvidersAny(t *testing.T) {
+
+	type Lote struct {
+		Name      string
+		Address   string
+		Weather   string
+		ApiKey    string
+		Probe     bool
+		Down      []string
+		AddressOk bool
+		WeatherOk bool
+	}
+
+	table := []Lote{
+		{Name: "missing key falls back to openmeteo", AddressOk: true, WeatherOk: true},
+		{Name: "missing key and weatherapi only", Weather: "weatherapi", AddressOk: true, WeatherOk: false},
+		{Name: "one of each down", ApiKey: "test", Probe: true, Down: []string{"viacep.com.br", "api.weatherapi.com"}, AddressOk: true, WeatherOk: true},
+		{Name: "all down", Address: "viacep,brasilapi", ApiKey: "test", Probe: true, Down: []string{"viacep.com.br", "brasilapi.com.br", "api.weatherapi.com", "api.open-meteo.com"}, AddressOk: false, WeatherOk: false},
+		{Name: "down but not probed", ApiKey: "test", Down: []string{"api.weatherapi.com", "api.open-meteo.com"}, AddressOk: true, WeatherOk: true},
+	}
+
+	for _, item := range table {
+		t.Run(item.Name, func(t *testing.T) {
+
+			t.Setenv("ADDRESS_PROVIDERS", item.Address)
+			t.Setenv("WEATHER_PROVIDERS", item.Weather)
+			t.Setenv("WEATHER_API_KEY", item.ApiKey)
+
+			mockClient := &http.Client{Transport: hostRoundTripper(item.Down)}
+
+			assert.Equal(t, item.AddressOk, usecase.CheckAddressProviders(context.Background(), mockClient) == nil)
+			assert.Equal(t, item.WeatherOk, usecase.CheckWeatherProviders(context.Background(), mockClient, item.Probe) == nil)
+		})
+	}
+}
+
+// hostRoundTripper answers 503 for the hosts in down and 200 for the others.
+type hostRoundTripper []string
+
+func (h hostRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	status := http.StatusOK
+	if slices.Contains(h, r.URL.Host) {
+		status = http.StatusServiceUnavailable
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
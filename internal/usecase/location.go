@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+)
+
+// states maps the UF returned by the address providers to the state name
+// returned by the geocoders.
+var states = map[string]string{
+	"AC": "Acre",
+	"AL": "Alagoas",
+	"AP": "Amapá",
+	"AM": "Amazonas",
+	"BA": "Bahia",
+	"CE": "Ceará",
+	"DF": "Distrito Federal",
+	"ES": "Espírito Santo",
+	"GO": "Goiás",
+	"MA": "Maranhão",
+	"MT": "Mato Grosso",
+	"MS": "Mato Grosso do Sul",
+	"MG": "Minas Gerais",
+	"PA": "Pará",
+	"PB": "Paraíba",
+	"PR": "Paraná",
+	"PE": "Pernambuco",
+	"PI": "Piauí",
+	"RJ": "Rio de Janeiro",
+	"RN": "Rio Grande do Norte",
+	"RS": "Rio Grande do Sul",
+	"RO": "Rondônia",
+	"RR": "Roraima",
+	"SC": "Santa Catarina",
+	"SP": "São Paulo",
+	"SE": "Sergipe",
+	"TO": "Tocantins",
+}
+
+func stateName(uf string) string {
+	return states[strings.ToUpper(strings.TrimSpace(uf))]
+}
+
+// parseCoordinate returns nil for the empty or malformed coordinates some
+// providers send for zip codes they could not geocode.
+func parseCoordinate(v string) *float64 {
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return nil
+	}
+
+	return &f
+}
+
+func hasCoordinates(a dto.AddressDto) bool {
+	return a.Lat != nil && a.Lon != nil
+}
+
+func formatCoordinate(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// locationQuery is "lat,lon" when the address has coordinates and
+// "city,UF,Brazil" otherwise, so homonym cities resolve to the right state.
+func locationQuery(a dto.AddressDto) string {
+
+	if hasCoordinates(a) {
+		return formatCoordinate(*a.Lat) + "," + formatCoordinate(*a.Lon)
+	}
+
+	parts := []string{a.Localidade}
+	if a.Uf != "" {
+		parts = append(parts, a.Uf)
+	}
+
+	return strings.Join(append(parts, "Brazil"), ",")
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
+)
+
+const (
+	WeatherProviderWeatherApi = "weatherapi"
+	WeatherProviderOpenMeteo  = "openmeteo"
+
+	// bounds each attempt, so a hung provider can not hold the coalesced
+	// requests waiting on it
+	defaultWeatherProviderTimeout = 3 * time.Second
+)
+
+// WeatherProvider returns the current weather of an address. Any error makes
+// NewWeatherByAddress try the next provider.
+type WeatherProvider interface {
+	Name() string
+	Weather(ctx context.Context, client *http.Client, a dto.AddressDto) (*dto.WeatherDto, error)
+}
+
+var weatherProviders = map[string]WeatherProvider{
+	WeatherProviderWeatherApi: weatherApi{},
+	WeatherProviderOpenMeteo:  openMeteo{},
+}
+
+// WeatherProviders returns the providers in the order set by WEATHER_PROVIDERS
+// (e.g. "openmeteo"), skipping unknown names. Defaults to WeatherAPI and then
+// Open-Meteo.
+func WeatherProviders() []WeatherProvider {
+
+	names := []string{WeatherProviderWeatherApi, WeatherProviderOpenMeteo}
+	if v := os.Getenv("WEATHER_PROVIDERS"); v != "" {
+		names = strings.Split(v, ",")
+	}
+
+	var providers []WeatherProvider
+	for _, name := range names {
+		p, ok := weatherProviders[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			slog.Warn("[unknown weather provider]", "name", name)
+			continue
+		}
+		providers = append(providers, p)
+	}
+
+	return providers
+}
+
+type weatherApi struct{}
+
+func (weatherApi) Name() string { return WeatherProviderWeatherApi }
+
+func (weatherApi) Weather(ctx context.Context, client *http.Client, a dto.AddressDto) (*dto.WeatherDto, error) {
+
+	key := os.Getenv("WEATHER_API_KEY")
+	if key == "" {
+		return nil, errors.New("WEATHER_API_KEY is not set")
+	}
+
+	var w dto.WeatherApiDto
+	err := getJson(ctx, client, "https://api.weatherapi.com/v1/current.json", map[string]string{
+		"key": key,
+		"q":   locationQuery(a),
+		"aqi": "no",
+	}, &w)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.WeatherDto{
+		Location: w.Location.Name,
+		Region:   w.Location.Region,
+		Lat:      w.Location.Lat,
+		Lon:      w.Location.Lon,
+		TempC:    w.Current.TempC,
+		Provider: WeatherProviderWeatherApi,
+	}, nil
+}
+
+// openMeteo needs no key. Addresses without coordinates are geocoded first,
+// keeping only the places in the address' state.
+type openMeteo struct{}
+
+func (openMeteo) Name() string { return WeatherProviderOpenMeteo }
+
+func (openMeteo) Weather(ctx context.Context, client *http.Client, a dto.AddressDto) (*dto.WeatherDto, error) {
+
+	w := dto.WeatherDto{Location: a.Localidade, Region: stateName(a.Uf), Provider: WeatherProviderOpenMeteo}
+
+	if hasCoordinates(a) {
+		w.Lat, w.Lon = *a.Lat, *a.Lon
+	} else {
+		var g dto.OpenMeteoGeocodingDto
+		err := getJson(ctx, client, "https://geocoding-api.open-meteo.com/v1/search", map[string]string{
+			"name":        a.Localidade,
+			"countryCode": "BR",
+			"count":       "10",
+			"language":    "pt",
+		}, &g)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		for _, place := range g.Results {
+			if w.Region == "" || strings.EqualFold(place.Admin1, w.Region) {
+				w.Location, w.Region, w.Lat, w.Lon = place.Name, place.Admin1, place.Latitude, place.Longitude
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("open-meteo: location not found: " + locationQuery(a))
+		}
+	}
+
+	var f dto.OpenMeteoForecastDto
+	err := getJson(ctx, client, "https://api.open-meteo.com/v1/forecast", map[string]string{
+		"latitude":  formatCoordinate(w.Lat),
+		"longitude": formatCoordinate(w.Lon),
+		"current":   "temperature_2m",
+	}, &f)
+	if err != nil {
+		return nil, err
+	}
+	w.TempC = f.Current.Temperature2m
+
+	return &w, nil
+}
@@ -3,54 +3,106 @@ package usecase
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/domainerr"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
+const weatherProviderKey = attribute.Key("weather.provider")
+
+// NewWeatherByAddress serves the weather from the cache for CACHE_WEATHER_TTL
+// (5m by default), keyed by the same location the providers are asked for.
 func NewWeatherByAddress(ctx context.Context, tracer trace.Tracer, a dto.AddressDto, client *http.Client) (*dto.WeatherDto, error) {
 
-	ctx, span := tracer.Start(ctx, "NewWeatherByServiceB")
+	ctx, span := tracer.Start(ctx, "NewWeatherByAddress")
 	defer span.End()
 
-	var urlQuery = map[string]string{}
-	urlQuery["key"] = os.Getenv("WEATHER_API_KEY")
-	urlQuery["q"] = a.Localidade
-	urlQuery["aqi"] = "no"
-
-	wcReq, err := webclient.NewWebclient(ctx, client, http.MethodGet, "https://api.weatherapi.com/v1/current.json", urlQuery)
+	w, info, err := cached(ctx, span, cacheTypeWeather, locationQuery(a), durationFromEnv("CACHE_WEATHER_TTL", defaultWeatherCacheTTL), func(ctx context.Context) (*dto.WeatherDto, error) {
+		return weatherFromProviders(ctx, tracer, span, a, client)
+	})
 	if err != nil {
-		slog.Error("[weatherapi webserver client]", "error", err.Error())
 		return nil, err
 	}
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(wcReq.Request().Header))
+	w.Cache = info
 
-	slog.Debug("[wcReq.Request().Header]", "Header", wcReq.Request().Header)
-
-	var w dto.WeatherDto
+	return w, nil
+}
 
-	err = wcReq.Do(func(p []byte) error {
-		err = json.Unmarshal(p, &w)
-		if err != nil {
-			slog.Error("[weather body unmarshal]", "error", err.Error())
+// weatherFromProviders asks the WeatherProviders in order and falls back to
+// the next one when a provider fails.
+func weatherFromProviders(ctx context.Context, tracer trace.Tracer, span trace.Span, a dto.AddressDto, client *http.Client) (*dto.WeatherDto, error) {
+
+	var errs []error
+	for _, p := range WeatherProviders() {
+
+		w, err := weatherFromProvider(ctx, tracer, p, a, client)
+		if err == nil {
+			span.SetAttributes(
+				weatherProviderKey.String(p.Name()),
+				attribute.String("weather.location.name", w.Location),
+				attribute.String("weather.location.region", w.Region),
+				attribute.Float64("weather.location.lat", w.Lat),
+				attribute.Float64("weather.location.lon", w.Lon),
+			)
+			if region := stateName(a.Uf); region != "" && !strings.EqualFold(region, w.Region) {
+				slog.WarnContext(ctx, "[weather location mismatch]", "provider", p.Name(), "uf", a.Uf, "region", w.Region)
+			}
+			slog.DebugContext(ctx, "[struct]", "WeatherDto", w)
+			return w, nil
 		}
-		return err
-	})
-	if err != nil {
-		slog.Error("[weather do]", "error", err.Error())
-		return nil, err
 
+		slog.WarnContext(ctx, "[weather provider failed]", "provider", p.Name(), "error", err.Error())
+		errs = append(errs, err)
+	}
+
+	err := errors.Join(errs...)
+	if err == nil {
+		err = errors.New("no weather provider configured")
 	}
 
-	slog.Debug("[struct]", "WeatherResponseDto", w)
+	slog.ErrorContext(ctx, "[weather providers failed]", "error", err.Error())
+	span.RecordError(err)
+	span.SetStatus(codes.Error, "all weather providers failed")
 
-	return &w, nil
+	return nil, err
+}
+
+func weatherFromProvider(ctx context.Context, tracer trace.Tracer, p WeatherProvider, a dto.AddressDto, client *http.Client) (*dto.WeatherDto, error) {
+
+	ctx, span := tracer.Start(ctx, "WeatherProvider "+p.Name(), trace.WithAttributes(weatherProviderKey.String(p.Name())))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, durationFromEnv("WEATHER_PROVIDER_TIMEOUT", defaultWeatherProviderTimeout))
+	defer cancel()
+
+	w, err := p.Weather(ctx, client, a)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return w, err
+}
+
+// serviceBRetryPolicy only retries the 503s service-b answers with
+// Retry-After, when it sheds load or a breaker is open. Its other failures
+// already went through its own retries on each provider, so retrying them
+// here would multiply the calls to the providers.
+var serviceBRetryPolicy = webclient.RetryPolicy{
+	MaxAttempts:       2,
+	MaxDelay:          2 * time.Second,
+	RetryableStatus:   []int{http.StatusServiceUnavailable},
+	RequireRetryAfter: true,
 }
 
 func NewWeatherByServiceB(ctx context.Context, tracer trace.Tracer, cli *http.Client, z dto.ZipcodeDto) (*dto.LocalWeatherDto, error) {
@@ -58,24 +110,34 @@ func NewWeatherByServiceB(ctx context.Context, tracer trace.Tracer, cli *http.Cl
 	ctx, span := tracer.Start(ctx, "NewWeatherByServiceB")
 	defer span.End()
 
-	wcReq, err := webclient.NewWebclient(ctx, cli, http.MethodGet, "http://"+os.Getenv("SERVICE_B_HOST")+":"+os.Getenv("SERVICE_B_PORT")+"/zipcode/"+z.Zipcode, nil)
+	wcReq, err := webclient.NewWebclient(ctx, cli, http.MethodGet, "http://"+os.Getenv("SERVICE_B_HOST")+":"+os.Getenv("SERVICE_B_PORT")+"/zipcode/"+z.Zipcode, nil, webclient.WithRetryPolicy(serviceBRetryPolicy))
 	if err != nil {
-		slog.Error("[service b webclient]", "error", err.Error())
+		slog.ErrorContext(ctx, "[service b webclient]", "error", err.Error())
 		return nil, err
 	}
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(wcReq.Request().Header))
 
 	var l dto.LocalWeatherDto
 
 	err = wcReq.Do(func(p []byte) error {
 		err = json.Unmarshal(p, &l)
 		if err != nil {
-			slog.Error("[service b body unmarshal]", "error", err.Error())
+			slog.ErrorContext(ctx, "[service b body unmarshal]", "error", err.Error())
 		}
 		return err
 	})
 	if err != nil {
-		slog.Error("[service b webclient do]", "error", err.Error())
+		slog.ErrorContext(ctx, "[service b webclient do]", "error", err.Error())
+
+		switch status := upstreamStatus(err); status {
+		case http.StatusNotFound:
+			err = domainerr.Wrap(domainerr.ErrZipcodeNotFound, status, err)
+		case http.StatusUnprocessableEntity:
+			err = domainerr.Wrap(domainerr.ErrInvalidZipcode, status, err)
+		default:
+			err = upstreamError(err)
+		}
+
+		span.SetStatus(domainerr.SpanStatus(err))
 		return nil, err
 	}
 
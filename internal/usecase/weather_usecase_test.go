@@ -4,22 +4,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/domainerr"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/mockup"
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/usecase"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestNewWeatherByAddressSuccess(t *testing.T) {
 
-	mockWeatherResponseSuccessBody := `{"Location":{"name":"San Paulo","region":"Sao Paulo"},"Current":{"temp_c":14.2}}`
+	usecase.SetCache(usecase.NewMemoryCache(16))
+
+	t.Setenv("WEATHER_API_KEY", "test")
+
+	mockWeatherResponseSuccessBody := `{"Location":{"name":"San Paulo","region":"Sao Paulo","lat":-23.53,"lon":-46.62},"Current":{"temp_c":14.2}}`
+	mockWeatherSuccess := `{"location":"San Paulo","region":"Sao Paulo","lat":-23.53,"lon":-46.62,"temp_c":14.2,"provider":"weatherapi"}`
 	mockAddressSuccess := dto.AddressDto{
 		Cep:        "01001-000",
 		Localidade: "São Paulo",
@@ -42,11 +54,15 @@ func TestNewWeatherByAddressSuccess(t *testing.T) {
 	wea, err := json.Marshal(weatherDto)
 	assert.Nil(t, err)
 
-	assert.Equal(t, []byte(mockWeatherResponseSuccessBody), wea)
+	assert.Equal(t, []byte(mockWeatherSuccess), wea)
 }
 
 func TestNewWeatherByAddressAddressNotFount(t *testing.T) {
 
+	usecase.SetCache(usecase.NewMemoryCache(16))
+
+	t.Setenv("WEATHER_API_KEY", "test")
+
 	slog.SetLogLoggerLevel(slog.LevelDebug)
 
 	mockWeatherResponseErrorBody := "api.weatherapi.com: "
@@ -79,3 +95,187 @@ func TestNewWeatherByAddressAddressNotFount(t *testing.T) {
 
 	assert.Contains(t, err.Error(), "Bad Request")
 }
+
+func TestNewWeatherByAddressFallback(t *testing.T) {
+
+	type Lote struct {
+		Name      string
+		Providers string
+		ApiKey    string
+		Location  string
+		TempC     float64
+		Provider  string
+		Attempts  []string
+	}
+
+	table := []Lote{
+		{Name: "weatherapi down", ApiKey: "test", Location: "São Paulo", TempC: 21.3, Provider: "openmeteo", Attempts: []string{"weatherapi", "openmeteo"}},
+		{Name: "missing key", Location: "São Paulo", TempC: 21.3, Provider: "openmeteo", Attempts: []string{"weatherapi", "openmeteo"}},
+		{Name: "openmeteo only", Providers: "openmeteo", ApiKey: "test", Location: "São Paulo", TempC: 21.3, Provider: "openmeteo", Attempts: []string{"openmeteo"}},
+	}
+
+	responses := map[string]string{
+		"api.weatherapi.com":           "",
+		"geocoding-api.open-meteo.com": `{"results":[{"name":"São Paulo","latitude":-23.5475,"longitude":-46.63611,"admin1":"São Paulo"}]}`,
+		"api.open-meteo.com":           `{"current":{"temperature_2m":21.3}}`,
+	}
+
+	for _, item := range table {
+		t.Run(item.Name, func(t *testing.T) {
+
+			usecase.SetCache(usecase.NewMemoryCache(16))
+
+			t.Setenv("WEATHER_API_KEY", item.ApiKey)
+			t.Setenv("WEATHER_PROVIDERS", item.Providers)
+
+			mockRoundTripper := new(mockup.MockRoundTripper)
+			for host, body := range responses {
+				resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+				if body == "" {
+					resp.StatusCode = http.StatusServiceUnavailable
+				}
+				mockRoundTripper.On("RoundTrip", mock.MatchedBy(func(r *http.Request) bool { return r.URL.Host == host })).Return(resp, nil)
+			}
+			mockClient := &http.Client{Transport: mockRoundTripper}
+
+			rec := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+
+			weatherDto, err := usecase.NewWeatherByAddress(context.Background(), tracer, dto.AddressDto{Cep: "01001-000", Localidade: "São Paulo"}, mockClient)
+			assert.Nil(t, err)
+			weatherDto.Cache = dto.CacheDto{}
+			assert.Equal(t, &dto.WeatherDto{Location: item.Location, Region: "São Paulo", Lat: -23.5475, Lon: -46.63611, TempC: item.TempC, Provider: item.Provider}, weatherDto)
+
+			var attempts []string
+			for _, s := range rec.Ended() {
+				if strings.HasPrefix(s.Name(), "WeatherProvider ") {
+					attrs := attribute.NewSet(s.Attributes()...)
+					provider, _ := attrs.Value("weather.provider")
+					attempts = append(attempts, provider.AsString())
+				}
+			}
+			assert.Equal(t, item.Attempts, attempts)
+		})
+	}
+}
+
+func TestNewWeatherByAddressLocation(t *testing.T) {
+
+	t.Setenv("WEATHER_API_KEY", "test")
+
+	lat, lon := -29.6868, -53.8149
+
+	type Lote struct {
+		Name      string
+		Providers string
+		Address   dto.AddressDto
+		Query     string
+		Weather   dto.WeatherDto
+	}
+
+	table := []Lote{
+		{
+			Name:      "weatherapi by coordinates",
+			Providers: "weatherapi",
+			Address:   dto.AddressDto{Localidade: "Santa Maria", Uf: "RS", Lat: &lat, Lon: &lon},
+			Query:     "-29.6868,-53.8149",
+			Weather:   dto.WeatherDto{Location: "Santa Maria", Region: "Rio Grande do Sul", Lat: -29.68, Lon: -53.81, TempC: 18, Provider: "weatherapi"},
+		},
+		{
+			Name:      "weatherapi by city and uf",
+			Providers: "weatherapi",
+			Address:   dto.AddressDto{Localidade: "Santa Maria", Uf: "RS"},
+			Query:     "Santa Maria,RS,Brazil",
+			Weather:   dto.WeatherDto{Location: "Santa Maria", Region: "Rio Grande do Sul", Lat: -29.68, Lon: -53.81, TempC: 18, Provider: "weatherapi"},
+		},
+		{
+			Name:      "openmeteo by coordinates",
+			Providers: "openmeteo",
+			Address:   dto.AddressDto{Localidade: "Santa Maria", Uf: "RS", Lat: &lat, Lon: &lon},
+			Query:     "-29.6868",
+			Weather:   dto.WeatherDto{Location: "Santa Maria", Region: "Rio Grande do Sul", Lat: lat, Lon: lon, TempC: 17.5, Provider: "openmeteo"},
+		},
+		{
+			Name:      "openmeteo geocodes within the uf",
+			Providers: "openmeteo",
+			Address:   dto.AddressDto{Localidade: "Santa Maria", Uf: "RS"},
+			Query:     "-29.68417",
+			Weather:   dto.WeatherDto{Location: "Santa Maria", Region: "Rio Grande do Sul", Lat: -29.68417, Lon: -53.80694, TempC: 17.5, Provider: "openmeteo"},
+		},
+	}
+
+	for _, item := range table {
+		t.Run(item.Name, func(t *testing.T) {
+
+			usecase.SetCache(usecase.NewMemoryCache(16))
+
+			t.Setenv("WEATHER_PROVIDERS", item.Providers)
+
+			var queries []string
+			responses := map[string]string{
+				"api.weatherapi.com":           `{"Location":{"name":"Santa Maria","region":"Rio Grande do Sul","lat":-29.68,"lon":-53.81},"Current":{"temp_c":18}}`,
+				"geocoding-api.open-meteo.com": `{"results":[{"name":"Santa Maria","latitude":-15.87,"longitude":-48.02,"admin1":"Distrito Federal"},{"name":"Santa Maria","latitude":-29.68417,"longitude":-53.80694,"admin1":"Rio Grande do Sul"}]}`,
+				"api.open-meteo.com":           `{"current":{"temperature_2m":17.5}}`,
+			}
+
+			mockRoundTripper := new(mockup.MockRoundTripper)
+			for host, body := range responses {
+				mockRoundTripper.On("RoundTrip", mock.MatchedBy(func(r *http.Request) bool {
+					if r.URL.Host != host {
+						return false
+					}
+					queries = append(queries, r.URL.Query().Get("q")+r.URL.Query().Get("latitude"))
+					return true
+				})).Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil)
+			}
+			mockClient := &http.Client{Transport: mockRoundTripper}
+
+			weatherDto, err := usecase.NewWeatherByAddress(context.Background(), otel.Tracer("test"), item.Address, mockClient)
+			assert.Nil(t, err)
+			weatherDto.Cache = dto.CacheDto{}
+			assert.Equal(t, &item.Weather, weatherDto)
+			assert.Contains(t, queries, item.Query)
+		})
+	}
+}
+
+func TestNewWeatherByServiceBErrors(t *testing.T) {
+
+	type Lote struct {
+		Status int
+		Err    error
+	}
+
+	table := []Lote{
+		{Status: http.StatusNotFound, Err: domainerr.ErrZipcodeNotFound},
+		{Status: http.StatusUnprocessableEntity, Err: domainerr.ErrInvalidZipcode},
+		{Status: http.StatusTooManyRequests, Err: domainerr.ErrUpstreamRateLimited},
+		{Status: http.StatusInternalServerError, Err: domainerr.ErrUpstreamUnavailable},
+	}
+
+	for _, item := range table {
+		t.Run(http.StatusText(item.Status), func(t *testing.T) {
+
+			t.Setenv("SERVICE_B_HOST", "service-b")
+			webclient.DefaultBreakers.Reset()
+
+			mockRoundTripper := new(mockup.MockRoundTripper)
+			mockRoundTripper.On("RoundTrip", mock.Anything).Return(&http.Response{
+				StatusCode: item.Status,
+				Body:       io.NopCloser(strings.NewReader(`{"msg":"error"}`)),
+			}, nil)
+			mockClient := &http.Client{Transport: mockRoundTripper}
+
+			localWeatherDto, err := usecase.NewWeatherByServiceB(context.Background(), otel.Tracer("test"), mockClient, dto.ZipcodeDto{Zipcode: "01001000"})
+			assert.Nil(t, localWeatherDto)
+			assert.True(t, errors.Is(err, item.Err), err.Error())
+
+			// service-b already retried its providers, so no Retry-After means no retry
+			mockRoundTripper.AssertNumberOfCalls(t, "RoundTrip", 1)
+
+			var derr *domainerr.Error
+			assert.True(t, errors.As(err, &derr))
+			assert.Equal(t, item.Status, derr.StatusCode)
+		})
+	}
+}
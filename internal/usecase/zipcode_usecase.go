@@ -2,48 +2,85 @@ package usecase
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
 
+	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/domainerr"
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/dto"
-	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/infra/webclient"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
+const addressProviderKey = attribute.Key("address.provider")
+
+// NewAddressByZipcode serves the address from the cache for CACHE_ADDRESS_TTL
+// (24h by default), since zip codes practically never move.
 func NewAddressByZipcode(ctx context.Context, tracer trace.Tracer, z dto.ZipcodeDto, client *http.Client) (*dto.AddressDto, error) {
 
 	ctx, span := tracer.Start(ctx, "NewAddressByZipcode")
 	defer span.End()
 
-	wcReq, err := webclient.NewWebclient(ctx, client, http.MethodGet, "https://viacep.com.br/ws/"+z.Zipcode+"/json/", nil)
+	a, info, err := cached(ctx, span, cacheTypeAddress, z.Zipcode, durationFromEnv("CACHE_ADDRESS_TTL", defaultAddressCacheTTL), func(ctx context.Context) (*dto.AddressDto, error) {
+		return addressFromProviders(ctx, tracer, span, z, client)
+	})
 	if err != nil {
-		slog.Error("[viacep NewWebclient failed]", "error", err.Error())
 		return nil, err
 	}
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(wcReq.Request().Header))
+	a.Cache = info
+
+	return a, nil
+}
+
+// addressFromProviders asks the AddressProviders in order and falls back to
+// the next one on errors and timeouts. A not found answer is final.
+func addressFromProviders(ctx context.Context, tracer trace.Tracer, span trace.Span, z dto.ZipcodeDto, client *http.Client) (*dto.AddressDto, error) {
 
-	var a dto.AddressDto
+	var errs []error
+	for _, p := range AddressProviders() {
 
-	err = wcReq.Do(func(p []byte) error {
-		err = json.Unmarshal(p, &a)
-		if err != nil {
-			slog.Error("[zipcode body unmarshal]", "error", err.Error())
+		a, err := addressFromProvider(ctx, tracer, p, z, client)
+		if err == nil {
+			span.SetAttributes(addressProviderKey.String(p.Name()))
+			slog.DebugContext(ctx, "[zipcode body]", "provider", p.Name(), "body", a)
+			return a, nil
+		}
+
+		if errors.Is(err, domainerr.ErrZipcodeNotFound) {
+			span.SetAttributes(addressProviderKey.String(p.Name()))
+			return nil, err
 		}
-		return err
-	})
-	if err != nil {
-		slog.Error("[webclient do]", "error", err.Error())
 
+		slog.WarnContext(ctx, "[address provider failed]", "provider", p.Name(), "error", err.Error())
+		errs = append(errs, err)
 	}
-	slog.Debug("[zipcode body]", "body", a)
 
-	if a.Error != "" {
-		return nil, errors.New("zip code not found")
+	err := errors.Join(errs...)
+	if err == nil {
+		err = errors.New("no address provider configured")
+	}
+
+	slog.ErrorContext(ctx, "[address providers failed]", "error", err.Error())
+	span.RecordError(err)
+	span.SetStatus(codes.Error, "all address providers failed")
+
+	return nil, err
+}
+
+func addressFromProvider(ctx context.Context, tracer trace.Tracer, p AddressProvider, z dto.ZipcodeDto, client *http.Client) (*dto.AddressDto, error) {
+
+	ctx, span := tracer.Start(ctx, "AddressProvider "+p.Name(), trace.WithAttributes(addressProviderKey.String(p.Name())))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, durationFromEnv("ADDRESS_PROVIDER_TIMEOUT", defaultAddressProviderTimeout))
+	defer cancel()
+
+	a, err := p.Address(ctx, client, z)
+	if err != nil && !errors.Is(err, domainerr.ErrZipcodeNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
 
-	return &a, err
+	return a, err
 }
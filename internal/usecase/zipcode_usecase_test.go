@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/felipeksw/goexpert-fullcycle-cloud-run/internal/entity"
@@ -14,12 +15,17 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestNewAddressByZipcodeSuccess(t *testing.T) {
 
+	usecase.SetCache(usecase.NewMemoryCache(16))
+
 	mockCepSuccess := "01001000"
-	mockZipcodeResponseSuccessBody := `{"cep":"01001-000","localidade":"São Paulo","erro":""}`
+	mockZipcodeResponseSuccessBody := `{"cep":"01001-000","localidade":"São Paulo","uf":"SP","erro":""}`
 
 	mockRoundTripper := new(mockup.MockRoundTripper)
 	mockClient := &http.Client{Transport: mockRoundTripper}
@@ -45,6 +51,8 @@ func TestNewAddressByZipcodeSuccess(t *testing.T) {
 
 func TestNewAddressByZipcodeCepNotFound(t *testing.T) {
 
+	usecase.SetCache(usecase.NewMemoryCache(16))
+
 	mockCepSuccess := "01001009"
 	mockZipcodeResponseErrorBody := `{"erro":"true"}`
 
@@ -65,3 +73,100 @@ func TestNewAddressByZipcodeCepNotFound(t *testing.T) {
 	assert.Equal(t, "zip code not found", err.Error())
 	assert.Nil(t, addressDto)
 }
+
+func TestNewAddressByZipcodeFallback(t *testing.T) {
+
+	type Lote struct {
+		Name       string
+		Providers  string
+		Responses  map[string]*http.Response
+		Localidade string
+		Error      string
+		Attempts   []string
+	}
+
+	response := func(code int, body string) *http.Response {
+		return &http.Response{StatusCode: code, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	table := []Lote{
+		{
+			Name: "viacep down",
+			Responses: map[string]*http.Response{
+				"viacep.com.br":    response(http.StatusServiceUnavailable, ""),
+				"brasilapi.com.br": response(http.StatusOK, `{"cep":"01001000","state":"SP","city":"São Paulo","location":{"type":"Point","coordinates":{"longitude":"-46.6340","latitude":"-23.5505"}}}`),
+			},
+			Localidade: "São Paulo",
+			Attempts:   []string{"viacep", "brasilapi"},
+		},
+		{
+			Name:      "configured order",
+			Providers: "awesomeapi, viacep",
+			Responses: map[string]*http.Response{
+				"cep.awesomeapi.com.br": response(http.StatusOK, `{"cep":"01001000","state":"SP","city":"São Paulo"}`),
+			},
+			Localidade: "São Paulo",
+			Attempts:   []string{"awesomeapi"},
+		},
+		{
+			Name:      "not found is final",
+			Providers: "brasilapi,awesomeapi",
+			Responses: map[string]*http.Response{
+				"brasilapi.com.br": response(http.StatusNotFound, `{"message":"CEP não encontrado"}`),
+			},
+			Error:    "zip code not found: brasilapi.com.br: Not Found",
+			Attempts: []string{"brasilapi"},
+		},
+		{
+			Name:      "all providers down",
+			Providers: "viacep,awesomeapi",
+			Responses: map[string]*http.Response{
+				"viacep.com.br":         response(http.StatusBadGateway, ""),
+				"cep.awesomeapi.com.br": response(http.StatusInternalServerError, ""),
+			},
+			Error:    "upstream unavailable: viacep.com.br: Bad Gateway\nupstream unavailable: cep.awesomeapi.com.br: Internal Server Error",
+			Attempts: []string{"viacep", "awesomeapi"},
+		},
+	}
+
+	for _, item := range table {
+		t.Run(item.Name, func(t *testing.T) {
+
+			usecase.SetCache(usecase.NewMemoryCache(16))
+
+			t.Setenv("ADDRESS_PROVIDERS", item.Providers)
+
+			mockRoundTripper := new(mockup.MockRoundTripper)
+			for host, resp := range item.Responses {
+				mockRoundTripper.On("RoundTrip", mock.MatchedBy(func(r *http.Request) bool { return r.URL.Host == host })).Return(resp, nil)
+			}
+			mockClient := &http.Client{Transport: mockRoundTripper}
+
+			rec := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+
+			zipcodeDto, err := entity.NewZipcode("01001000")
+			assert.Nil(t, err)
+
+			addressDto, err := usecase.NewAddressByZipcode(context.Background(), tracer, *zipcodeDto, mockClient)
+			if item.Error != "" {
+				assert.Nil(t, addressDto)
+				assert.Equal(t, item.Error, err.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, item.Localidade, addressDto.Localidade)
+				assert.Equal(t, "SP", addressDto.Uf)
+			}
+
+			var attempts []string
+			for _, s := range rec.Ended() {
+				if strings.HasPrefix(s.Name(), "AddressProvider ") {
+					attrs := attribute.NewSet(s.Attributes()...)
+					provider, _ := attrs.Value("address.provider")
+					attempts = append(attempts, provider.AsString())
+				}
+			}
+			assert.Equal(t, item.Attempts, attempts)
+		})
+	}
+}
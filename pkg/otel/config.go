@@ -0,0 +1,354 @@
+package otel
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	envExporterProtocol = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	envExporterEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envExporterInsecure = "OTEL_EXPORTER_OTLP_INSECURE"
+	envExporterHeaders  = "OTEL_EXPORTER_OTLP_HEADERS"
+	envExporterTimeout  = "OTEL_EXPORTER_OTLP_TIMEOUT"
+	envLogsExporter     = "OTEL_LOGS_EXPORTER"
+
+	defaultGRPCEndpoint = "http://localhost:4317"
+	defaultHTTPEndpoint = "http://localhost:4318"
+	defaultTimeout      = 10 * time.Second
+)
+
+type Protocol string
+
+const (
+	ProtocolGRPC         Protocol = "grpc"
+	ProtocolHTTPProtobuf Protocol = "http/protobuf"
+)
+
+type config struct {
+	serviceName    string
+	serviceVersion string
+	environment    string
+	attributes     []attribute.KeyValue
+
+	protocol   Protocol
+	endpoint   string
+	insecure   *bool
+	tls        TLSConfig
+	headers    map[string]string
+	headerFunc func(context.Context) (map[string]string, error)
+	timeout    time.Duration
+
+	maxExportBatchSize int
+	maxQueueSize       int
+	batchTimeout       time.Duration
+
+	sampler sdktrace.Sampler
+
+	propagators []string
+	baggageKeys []string
+
+	logs bool
+}
+
+type Option func(*config)
+
+// newConfig applies the defaults, then the OTEL_* environment variables and
+// finally the options, so code always has the last word over the exporter.
+// The resource is the exception: OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES
+// are merged by the SDK on top of the attributes given here.
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		protocol:    ProtocolGRPC,
+		timeout:     defaultTimeout,
+		propagators: defaultPropagators,
+		logs:        true,
+	}
+	cfg.loadEnv()
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.endpoint == "" {
+		cfg.endpoint = defaultGRPCEndpoint
+		if cfg.protocol == ProtocolHTTPProtobuf {
+			cfg.endpoint = defaultHTTPEndpoint
+		}
+	}
+
+	if cfg.sampler == nil {
+		cfg.sampler = samplerFromEnv()
+	}
+
+	return cfg
+}
+
+func (c *config) loadEnv() {
+
+	if v := strings.TrimSpace(os.Getenv(envExporterProtocol)); v != "" {
+		switch Protocol(v) {
+		case ProtocolGRPC, ProtocolHTTPProtobuf:
+			c.protocol = Protocol(v)
+		default:
+			slog.Warn("[invalid env]", "env", envExporterProtocol, "value", v)
+		}
+	}
+
+	if v := strings.TrimSpace(os.Getenv(envExporterEndpoint)); v != "" {
+		c.endpoint = v
+	}
+
+	if v := strings.TrimSpace(os.Getenv(envExporterInsecure)); v != "" {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			slog.Warn("[invalid env]", "env", envExporterInsecure, "error", err.Error())
+		} else {
+			c.insecure = &insecure
+		}
+	}
+
+	c.logs = LogsEnabled()
+
+	if v := strings.TrimSpace(os.Getenv(envPropagators)); v != "" {
+		c.propagators = strings.Split(v, ",")
+	}
+
+	c.tls.CAFile = os.Getenv(envExporterCertificate)
+	c.tls.CertFile = os.Getenv(envExporterClientCertificate)
+	c.tls.KeyFile = os.Getenv(envExporterClientKey)
+
+	if v := os.Getenv(envExporterHeaders); v != "" {
+		c.headers = parseHeaders(v)
+	}
+
+	if v := strings.TrimSpace(os.Getenv(envExporterTimeout)); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 0 {
+			slog.Warn("[invalid env]", "env", envExporterTimeout, "value", v)
+		} else {
+			c.timeout = time.Duration(ms) * time.Millisecond
+		}
+	}
+}
+
+// LogsEnabled tells whether the logs are exported, which only
+// OTEL_LOGS_EXPORTER=none turns off. It lets main decide on NewBridgeHandler
+// before InitProvider runs.
+func LogsEnabled() bool {
+
+	switch v := strings.TrimSpace(os.Getenv(envLogsExporter)); v {
+	case "", "otlp":
+		return true
+	case "none":
+		return false
+	default:
+		slog.Warn("[invalid env]", "env", envLogsExporter, "value", v)
+		return true
+	}
+}
+
+// target returns the host:port of the collector and whether the connection
+// must be made without TLS. An http or https scheme in the endpoint wins over
+// the insecure flag.
+func (c *config) target() (string, bool) {
+
+	u, err := url.Parse(c.endpoint)
+	if err != nil || u.Host == "" {
+		return c.endpoint, c.plaintext()
+	}
+
+	switch u.Scheme {
+	case "http":
+		return u.Host, true
+	case "https":
+		return u.Host, false
+	}
+
+	return c.endpoint, c.plaintext()
+}
+
+// plaintext decides for an endpoint without scheme. The insecure flag wins
+// when set; otherwise the connection stays plain text, as it always was,
+// unless a TLS configuration was given.
+func (c *config) plaintext() bool {
+	if c.insecure != nil {
+		return *c.insecure
+	}
+	return c.tls == (TLSConfig{})
+}
+
+// urlPath appends the signal path (e.g. /v1/traces) to any base path of the
+// endpoint, as OTLP/HTTP expects for OTEL_EXPORTER_OTLP_ENDPOINT.
+func (c *config) urlPath(signal string) string {
+
+	u, err := url.Parse(c.endpoint)
+	if err != nil || u.Host == "" {
+		return "/v1/" + signal
+	}
+
+	return strings.TrimSuffix(u.Path, "/") + "/v1/" + signal
+}
+
+// parseHeaders reads the W3C baggage-like format used by OTEL_EXPORTER_OTLP_HEADERS.
+func parseHeaders(v string) map[string]string {
+
+	headers := map[string]string{}
+	for _, pair := range strings.Split(v, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			slog.Warn("[invalid header]", "env", envExporterHeaders, "header", pair)
+			continue
+		}
+
+		value, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			slog.Warn("[invalid header]", "env", envExporterHeaders, "header", key, "error", err.Error())
+			continue
+		}
+		headers[key] = value
+	}
+
+	return headers
+}
+
+func WithServiceName(name string) Option {
+	return func(c *config) {
+		c.serviceName = name
+	}
+}
+
+// WithServiceVersion is usually fed by -ldflags "-X main.version=...". When
+// empty, the version is read from the build info.
+func WithServiceVersion(version string) Option {
+	return func(c *config) {
+		c.serviceVersion = version
+	}
+}
+
+func WithDeploymentEnvironment(environment string) Option {
+	return func(c *config) {
+		c.environment = environment
+	}
+}
+
+func WithResourceAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attributes = append(c.attributes, attrs...)
+	}
+}
+
+// WithProtocol selects the OTLP transport, gRPC by default.
+func WithProtocol(protocol Protocol) Option {
+	return func(c *config) {
+		c.protocol = protocol
+	}
+}
+
+// WithEndpoint sets the collector address, either host:port or a URL
+// whose scheme selects between plain text (http) and TLS (https).
+func WithEndpoint(endpoint string) Option {
+	return func(c *config) {
+		c.endpoint = endpoint
+	}
+}
+
+func WithInsecure() Option {
+	return func(c *config) {
+		insecure := true
+		c.insecure = &insecure
+	}
+}
+
+// WithTLS configures the custom CA, client certificate and server name used
+// when the endpoint is not plain text.
+func WithTLS(t TLSConfig) Option {
+	return func(c *config) {
+		c.tls = t
+	}
+}
+
+func WithHeaders(headers map[string]string) Option {
+	return func(c *config) {
+		c.headers = headers
+	}
+}
+
+// WithHeaderFunc resolves headers on every export request, e.g. to refresh
+// a short-lived token. It requires a TLS connection. Over OTLP/HTTP the
+// function is resolved only once, when the exporters are created.
+func WithHeaderFunc(fn func(context.Context) (map[string]string, error)) Option {
+	return func(c *config) {
+		c.headerFunc = fn
+	}
+}
+
+func WithBearerToken(token string) Option {
+	return WithHeaderFunc(func(context.Context) (map[string]string, error) {
+		return map[string]string{"authorization": "Bearer " + token}, nil
+	})
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.timeout = timeout
+	}
+}
+
+// WithLogs turns the export of logs on or off, overriding
+// OTEL_LOGS_EXPORTER. With logs off no LoggerProvider is set.
+func WithLogs(enabled bool) Option {
+	return func(c *config) {
+		c.logs = enabled
+	}
+}
+
+// WithBatch tunes the span and log batch processors. Zero values keep the
+// SDK defaults, which also honor OTEL_BSP_* and OTEL_BLRP_*.
+func WithBatch(maxExportBatchSize, maxQueueSize int, timeout time.Duration) Option {
+	return func(c *config) {
+		c.maxExportBatchSize = maxExportBatchSize
+		c.maxQueueSize = maxQueueSize
+		c.batchTimeout = timeout
+	}
+}
+
+// WithSampler overrides the sampler configured by OTEL_TRACES_SAMPLER.
+func WithSampler(sampler sdktrace.Sampler) Option {
+	return func(c *config) {
+		c.sampler = sampler
+	}
+}
+
+// WithSamplingRatio samples root spans by trace ID ratio and follows the
+// parent decision for remote and local children.
+func WithSamplingRatio(ratio float64) Option {
+	return func(c *config) {
+		c.sampler = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+	}
+}
+
+// WithPropagators selects the propagators by their OTEL_PROPAGATORS names:
+// tracecontext, baggage, b3 (single header) and b3multi.
+func WithPropagators(names ...string) Option {
+	return func(c *config) {
+		c.propagators = names
+	}
+}
+
+// WithBaggageSpanAttributes copies the given baggage members onto the
+// attributes of every span, e.g. to filter traces by tenant.
+func WithBaggageSpanAttributes(keys ...string) Option {
+	return func(c *config) {
+		c.baggageKeys = append(c.baggageKeys, keys...)
+	}
+}
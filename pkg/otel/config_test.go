@@ -0,0 +1,134 @@
+package otel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConfigDefaults(t *testing.T) {
+
+	cfg := newConfig(nil)
+
+	target, plaintext := cfg.target()
+	assert.Equal(t, "localhost:4317", target)
+	assert.True(t, plaintext)
+	assert.Equal(t, defaultTimeout, cfg.timeout)
+	assert.Empty(t, cfg.headers)
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+
+	t.Setenv(envExporterEndpoint, "otel-collector:4317")
+	t.Setenv(envExporterInsecure, "true")
+	t.Setenv(envExporterHeaders, "api-key=secret,x-tenant = acme%20corp,invalid")
+	t.Setenv(envExporterTimeout, "2500")
+
+	cfg := newConfig(nil)
+
+	target, plaintext := cfg.target()
+	assert.Equal(t, "otel-collector:4317", target)
+	assert.True(t, plaintext)
+	assert.Equal(t, map[string]string{"api-key": "secret", "x-tenant": "acme corp"}, cfg.headers)
+	assert.Equal(t, 2500*time.Millisecond, cfg.timeout)
+}
+
+func TestNewConfigOptionsOverrideEnv(t *testing.T) {
+
+	t.Setenv(envExporterEndpoint, "http://otel-collector:4317")
+	t.Setenv(envExporterTimeout, "2500")
+
+	cfg := newConfig([]Option{
+		WithEndpoint("https://collector.example.com:4317"),
+		WithTimeout(time.Second),
+		WithHeaders(map[string]string{"authorization": "Bearer token"}),
+		WithBatch(256, 1024, 2*time.Second),
+	})
+
+	target, plaintext := cfg.target()
+	assert.Equal(t, "collector.example.com:4317", target)
+	assert.False(t, plaintext)
+	assert.Equal(t, time.Second, cfg.timeout)
+	assert.Equal(t, "Bearer token", cfg.headers["authorization"])
+	assert.Len(t, cfg.spanProcessorOptions(), 3)
+	assert.Len(t, cfg.logProcessorOptions(), 3)
+}
+
+func TestNewConfigInvalidEnv(t *testing.T) {
+
+	t.Setenv(envExporterInsecure, "maybe")
+	t.Setenv(envExporterTimeout, "-1")
+
+	cfg := newConfig([]Option{WithEndpoint("collector:4317")})
+
+	target, plaintext := cfg.target()
+	assert.Equal(t, "collector:4317", target)
+	assert.True(t, plaintext)
+	assert.Equal(t, defaultTimeout, cfg.timeout)
+	assert.Empty(t, cfg.spanProcessorOptions())
+}
+
+func TestNewConfigProtocol(t *testing.T) {
+
+	t.Setenv(envExporterProtocol, "http/protobuf")
+
+	cfg := newConfig(nil)
+
+	target, plaintext := cfg.target()
+	assert.Equal(t, ProtocolHTTPProtobuf, cfg.protocol)
+	assert.Equal(t, "localhost:4318", target)
+	assert.True(t, plaintext)
+	assert.Equal(t, "/v1/traces", cfg.urlPath("traces"))
+
+	cfg = newConfig([]Option{WithProtocol(ProtocolGRPC), WithEndpoint("https://collector.example.com/otlp/")})
+	assert.Equal(t, ProtocolGRPC, cfg.protocol)
+	assert.Equal(t, "/otlp/v1/logs", cfg.urlPath("logs"))
+
+	t.Setenv(envExporterProtocol, "http/json")
+	assert.Equal(t, ProtocolGRPC, newConfig(nil).protocol)
+}
+
+func TestNewConfigBareEndpoint(t *testing.T) {
+
+	type Lote struct {
+		Name      string
+		Insecure  string
+		TLS       TLSConfig
+		Plaintext bool
+	}
+
+	// an endpoint without scheme stays plain text unless TLS is asked for
+	table := []Lote{
+		{Name: "default", Plaintext: true},
+		{Name: "insecure", Insecure: "true", Plaintext: true},
+		{Name: "secure", Insecure: "false", Plaintext: false},
+		{Name: "tls configured", TLS: TLSConfig{CAFile: "ca.crt"}, Plaintext: false},
+		{Name: "insecure wins over tls", Insecure: "true", TLS: TLSConfig{CAFile: "ca.crt"}, Plaintext: true},
+	}
+
+	for _, item := range table {
+		t.Run(item.Name, func(t *testing.T) {
+			t.Setenv(envExporterInsecure, item.Insecure)
+
+			cfg := newConfig([]Option{WithEndpoint("otel-collector:4317"), WithTLS(item.TLS)})
+
+			_, plaintext := cfg.target()
+			assert.Equal(t, item.Plaintext, plaintext)
+		})
+	}
+}
+
+func TestNewConfigLogs(t *testing.T) {
+
+	assert.True(t, newConfig(nil).logs)
+	assert.False(t, newConfig([]Option{WithLogs(false)}).logs)
+
+	t.Setenv(envLogsExporter, "none")
+	assert.False(t, LogsEnabled())
+	assert.False(t, newConfig(nil).logs)
+	assert.True(t, newConfig([]Option{WithLogs(true)}).logs)
+
+	t.Setenv(envLogsExporter, "console")
+	assert.True(t, LogsEnabled())
+}
@@ -0,0 +1,182 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+)
+
+type exporters struct {
+	trace  sdktrace.SpanExporter
+	metric sdkmetric.Exporter
+	log    sdklog.Exporter // nil when the logs are not exported
+
+	// close releases what the exporters share, after the providers are shut down
+	close func() error
+}
+
+func newExporters(ctx context.Context, cfg *config) (*exporters, error) {
+
+	switch cfg.protocol {
+	case ProtocolGRPC:
+		return newGRPCExporters(ctx, cfg)
+	case ProtocolHTTPProtobuf:
+		return newHTTPExporters(ctx, cfg)
+	}
+
+	return nil, fmt.Errorf("unsupported protocol: %s", cfg.protocol)
+}
+
+func newGRPCExporters(ctx context.Context, cfg *config) (*exporters, error) {
+
+	target, _ := cfg.target()
+
+	creds, err := cfg.transportCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if cfg.headerFunc != nil {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(headerCredentials{headers: cfg.headerFunc}))
+	}
+
+	conn, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection to collector: %w", err)
+	}
+
+	traceExporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithGRPCConn(conn),
+		otlptracegrpc.WithHeaders(cfg.headers),
+		otlptracegrpc.WithTimeout(cfg.timeout),
+	)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	metricExporter, err := otlpmetricgrpc.New(ctx,
+		otlpmetricgrpc.WithGRPCConn(conn),
+		otlpmetricgrpc.WithHeaders(cfg.headers),
+		otlpmetricgrpc.WithTimeout(cfg.timeout),
+	)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+
+	exp := &exporters{
+		trace:  traceExporter,
+		metric: metricExporter,
+		close:  conn.Close,
+	}
+	if !cfg.logs {
+		return exp, nil
+	}
+
+	exp.log, err = otlploggrpc.New(ctx,
+		otlploggrpc.WithGRPCConn(conn),
+		otlploggrpc.WithHeaders(cfg.headers),
+		otlploggrpc.WithTimeout(cfg.timeout),
+	)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create log exporter: %w", err)
+	}
+
+	return exp, nil
+}
+
+func newHTTPExporters(ctx context.Context, cfg *config) (*exporters, error) {
+
+	host, plaintext := cfg.target()
+
+	headers := map[string]string{}
+	for k, v := range cfg.headers {
+		headers[k] = v
+	}
+	if cfg.headerFunc != nil {
+		dynamic, err := cfg.headerFunc(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve headers: %w", err)
+		}
+		for k, v := range dynamic {
+			headers[k] = v
+		}
+	}
+
+	traceOpts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(host),
+		otlptracehttp.WithURLPath(cfg.urlPath("traces")),
+		otlptracehttp.WithHeaders(headers),
+		otlptracehttp.WithTimeout(cfg.timeout),
+	}
+	metricOpts := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithEndpoint(host),
+		otlpmetrichttp.WithURLPath(cfg.urlPath("metrics")),
+		otlpmetrichttp.WithHeaders(headers),
+		otlpmetrichttp.WithTimeout(cfg.timeout),
+	}
+	logOpts := []otlploghttp.Option{
+		otlploghttp.WithEndpoint(host),
+		otlploghttp.WithURLPath(cfg.urlPath("logs")),
+		otlploghttp.WithHeaders(headers),
+		otlploghttp.WithTimeout(cfg.timeout),
+	}
+
+	if plaintext {
+		traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+		metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+		logOpts = append(logOpts, otlploghttp.WithInsecure())
+	} else {
+		tlsCfg, err := cfg.tls.build()
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure TLS: %w", err)
+		}
+		traceOpts = append(traceOpts, otlptracehttp.WithTLSClientConfig(tlsCfg))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithTLSClientConfig(tlsCfg))
+		logOpts = append(logOpts, otlploghttp.WithTLSClientConfig(tlsCfg))
+	}
+
+	traceExporter, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	// the exporters already built are shut down on failure, as the gRPC
+	// path closes its connection
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		traceExporter.Shutdown(ctx)
+		return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+
+	exp := &exporters{
+		trace:  traceExporter,
+		metric: metricExporter,
+		close:  func() error { return nil },
+	}
+	if !cfg.logs {
+		return exp, nil
+	}
+
+	exp.log, err = otlploghttp.New(ctx, logOpts...)
+	if err != nil {
+		traceExporter.Shutdown(ctx)
+		metricExporter.Shutdown(ctx)
+		return nil, fmt.Errorf("failed to create log exporter: %w", err)
+	}
+
+	return exp, nil
+}
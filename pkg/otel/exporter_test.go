@@ -0,0 +1,99 @@
+package otel_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	otelpkg "github.com/felipeksw/goexpert-fullcycle-cloud-run/pkg/otel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	lognoop "go.opentelemetry.io/otel/log/noop"
+)
+
+func TestInitProviderHTTPProtobuf(t *testing.T) {
+
+	var mu sync.Mutex
+	paths := map[string]string{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths[r.URL.Path] = r.Header.Get("Content-Type") + "|" + r.Header.Get("api-key")
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	exportSpan(t, 5*time.Second,
+		otelpkg.WithServiceName("test"),
+		otelpkg.WithProtocol(otelpkg.ProtocolHTTPProtobuf),
+		otelpkg.WithEndpoint(srv.URL+"/otlp/"),
+		otelpkg.WithHeaders(map[string]string{"api-key": "secret"}),
+	)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "application/x-protobuf|secret", paths["/otlp/v1/traces"])
+	assert.Contains(t, paths, "/otlp/v1/metrics")
+}
+
+func TestInitProviderLogs(t *testing.T) {
+
+	type Lote struct {
+		Name string
+		Logs bool
+	}
+
+	table := []Lote{
+		{Name: "exported", Logs: true},
+		{Name: "disabled", Logs: false},
+	}
+
+	for _, item := range table {
+		t.Run(item.Name, func(t *testing.T) {
+
+			var mu sync.Mutex
+			paths := map[string]bool{}
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				paths[r.URL.Path] = true
+				mu.Unlock()
+
+				w.Header().Set("Content-Type", "application/x-protobuf")
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			// a provider left by another test must not take the record
+			global.SetLoggerProvider(lognoop.NewLoggerProvider())
+
+			shutdown, err := otelpkg.InitProvider(context.Background(),
+				otelpkg.WithServiceName("test"),
+				otelpkg.WithProtocol(otelpkg.ProtocolHTTPProtobuf),
+				otelpkg.WithEndpoint(srv.URL),
+				otelpkg.WithLogs(item.Logs),
+			)
+			require.Nil(t, err)
+
+			var record log.Record
+			record.SetBody(log.StringValue("[test]"))
+			global.GetLoggerProvider().Logger("test").Emit(context.Background(), record)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			assert.Nil(t, shutdown(ctx))
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, item.Logs, paths["/v1/logs"])
+		})
+	}
+}
@@ -2,57 +2,108 @@ package otel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 )
 
-func InitProvider(ctx context.Context, serviceName, collectorURL string) (func(context.Context) error, error) {
+func InitProvider(ctx context.Context, opts ...Option) (func(context.Context) error, error) {
 
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName(serviceName),
-		),
-	)
+	cfg := newConfig(opts)
+
+	res, err := newResource(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+		return nil, err
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
-	// conn, err := grpc.DialContext(ctx, collectorURL,
-	// 	grpc.WithTransportCredentials(insecure.NewCredentials()),
-	// 	grpc.WithBlock(),
-	// )
 
-	conn, err := grpc.NewClient(collectorURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	propagator, err := newPropagator(cfg.propagators)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gRPC connection to collector: %w", err)
+		return nil, fmt.Errorf("failed to create propagator: %w", err)
 	}
 
-	traceExporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithGRPCConn(conn))
+	exp, err := newExporters(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+		return nil, err
 	}
 
-	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
-	tracerProvider := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	tpOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(cfg.sampler),
 		sdktrace.WithResource(res),
-		sdktrace.WithSpanProcessor(bsp),
+	}
+	if len(cfg.baggageKeys) > 0 {
+		tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(baggageSpanProcessor{keys: cfg.baggageKeys}))
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(exp.trace, cfg.spanProcessorOptions()...)
+	tracerProvider := sdktrace.NewTracerProvider(append(tpOpts, sdktrace.WithSpanProcessor(bsp))...)
+
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp.metric)),
 	)
+
+	shutdowns := []func(context.Context) error{tracerProvider.Shutdown, meterProvider.Shutdown}
+	if exp.log != nil {
+		loggerProvider := sdklog.NewLoggerProvider(
+			sdklog.WithResource(res),
+			sdklog.WithProcessor(sdklog.NewBatchProcessor(exp.log, cfg.logProcessorOptions()...)),
+		)
+		global.SetLoggerProvider(loggerProvider)
+		shutdowns = append(shutdowns, loggerProvider.Shutdown)
+	}
+
 	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+
+	otel.SetTextMapPropagator(propagator)
+
+	// the exporters may share a connection, so it is only closed once all providers are flushed
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, shutdown := range shutdowns {
+			errs = append(errs, shutdown(ctx))
+		}
+		return errors.Join(append(errs, exp.close())...)
+	}, nil
+}
+
+func (c *config) spanProcessorOptions() []sdktrace.BatchSpanProcessorOption {
+
+	var opts []sdktrace.BatchSpanProcessorOption
+	if c.maxExportBatchSize > 0 {
+		opts = append(opts, sdktrace.WithMaxExportBatchSize(c.maxExportBatchSize))
+	}
+	if c.maxQueueSize > 0 {
+		opts = append(opts, sdktrace.WithMaxQueueSize(c.maxQueueSize))
+	}
+	if c.batchTimeout > 0 {
+		opts = append(opts, sdktrace.WithBatchTimeout(c.batchTimeout))
+	}
 
-	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return opts
+}
+
+func (c *config) logProcessorOptions() []sdklog.BatchProcessorOption {
+
+	var opts []sdklog.BatchProcessorOption
+	if c.maxExportBatchSize > 0 {
+		opts = append(opts, sdklog.WithExportMaxBatchSize(c.maxExportBatchSize))
+	}
+	if c.maxQueueSize > 0 {
+		opts = append(opts, sdklog.WithMaxQueueSize(c.maxQueueSize))
+	}
+	if c.batchTimeout > 0 {
+		opts = append(opts, sdklog.WithExportInterval(c.batchTimeout))
+	}
 
-	return tracerProvider.Shutdown, nil
+	return opts
 }
@@ -0,0 +1,68 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const envPropagators = "OTEL_PROPAGATORS"
+
+var defaultPropagators = []string{"tracecontext", "baggage"}
+
+// newPropagator builds the composite propagator from the OTEL_PROPAGATORS
+// names. B3 extraction accepts both encodings, the name only selects the
+// one used to inject.
+func newPropagator(names []string) (propagation.TextMapPropagator, error) {
+
+	var props []propagation.TextMapPropagator
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "tracecontext":
+			props = append(props, propagation.TraceContext{})
+		case "baggage":
+			props = append(props, propagation.Baggage{})
+		case "b3":
+			props = append(props, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			props = append(props, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "none", "":
+		default:
+			return nil, fmt.Errorf("unsupported propagator: %s", name)
+		}
+	}
+
+	return propagation.NewCompositeTextMapPropagator(props...), nil
+}
+
+// baggageSpanProcessor copies the selected baggage members to the
+// attributes of every span started with that baggage in its context.
+type baggageSpanProcessor struct {
+	keys []string
+}
+
+func (p baggageSpanProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+
+	bag := baggage.FromContext(ctx)
+	for _, key := range p.keys {
+		if m := bag.Member(key); m.Key() != "" {
+			s.SetAttributes(attribute.String(key, m.Value()))
+		}
+	}
+}
+
+func (p baggageSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {}
+
+func (p baggageSpanProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func (p baggageSpanProcessor) ForceFlush(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,92 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewPropagator(t *testing.T) {
+
+	type propagatorLote struct {
+		names   []string
+		headers []string
+		fail    bool
+	}
+
+	table := []propagatorLote{
+		{defaultPropagators, []string{"traceparent", "baggage"}, false},
+		{[]string{"tracecontext", "baggage", "b3"}, []string{"traceparent", "baggage", "b3"}, false},
+		{[]string{"b3multi"}, []string{"X-B3-TraceId", "X-B3-SpanId", "X-B3-Sampled"}, false},
+		{[]string{"none"}, []string{}, false},
+		{[]string{"jaeger"}, nil, true},
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x02},
+		TraceFlags: trace.FlagsSampled,
+	})
+	member, _ := baggage.NewMemberRaw("tenant", "acme")
+	bag, _ := baggage.New(member)
+	ctx := baggage.ContextWithBaggage(trace.ContextWithSpanContext(context.Background(), sc), bag)
+
+	for _, item := range table {
+		prop, err := newPropagator(item.names)
+		if item.fail {
+			assert.Error(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+
+		h := http.Header{}
+		prop.Inject(ctx, propagation.HeaderCarrier(h))
+		assert.Len(t, h, len(item.headers))
+		for _, name := range item.headers {
+			assert.NotEmpty(t, h.Get(name), name)
+		}
+	}
+}
+
+func TestNewPropagatorExtractB3(t *testing.T) {
+
+	prop, err := newPropagator([]string{"tracecontext", "baggage", "b3"})
+	assert.Nil(t, err)
+
+	h := http.Header{}
+	h.Set("X-B3-TraceId", "0af7651916cd43dd8448eb211c80319c")
+	h.Set("X-B3-SpanId", "b7ad6b7169203331")
+	h.Set("X-B3-Sampled", "1")
+
+	sc := trace.SpanContextFromContext(prop.Extract(context.Background(), propagation.HeaderCarrier(h)))
+	assert.True(t, sc.IsRemote())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", sc.TraceID().String())
+}
+
+func TestBaggageSpanProcessor(t *testing.T) {
+
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(baggageSpanProcessor{keys: []string{"tenant", "client.app"}}),
+		sdktrace.WithSpanProcessor(rec),
+	)
+	defer tp.Shutdown(context.Background())
+
+	tenant, _ := baggage.NewMemberRaw("tenant", "acme")
+	other, _ := baggage.NewMemberRaw("session", "ignored")
+	bag, _ := baggage.New(tenant, other)
+
+	_, span := tp.Tracer("test").Start(baggage.ContextWithBaggage(context.Background(), bag), "TestBaggageSpanProcessor")
+	span.End()
+
+	assert.Len(t, rec.Ended(), 1)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("tenant", "acme")}, rec.Ended()[0].Attributes())
+}
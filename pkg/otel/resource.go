@@ -0,0 +1,92 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// newResource merges the detected host, OS, process and container attributes
+// with the configured ones. OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES
+// are applied last and win over everything else.
+func newResource(ctx context.Context, cfg *config) (*resource.Resource, error) {
+
+	attrs := cfg.attributes
+	if cfg.serviceName != "" {
+		attrs = append(attrs, semconv.ServiceName(cfg.serviceName))
+	}
+
+	version := cfg.serviceVersion
+	if version == "" {
+		version = buildVersion()
+	}
+	if version != "" {
+		attrs = append(attrs, semconv.ServiceVersion(version))
+	}
+
+	if cfg.environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(cfg.environment))
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithOS(),
+		resource.WithContainer(),
+		resource.WithProcessPID(),
+		resource.WithProcessExecutableName(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithProcessRuntimeDescription(),
+		resource.WithAttributes(attrs...),
+		resource.WithFromEnv(),
+	)
+	if errors.Is(err, resource.ErrPartialResource) {
+		// a detector that fails (e.g. no container ID outside docker) must not stop the service
+		slog.WarnContext(ctx, "[partial resource]", "error", err.Error())
+		return res, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	return res, nil
+}
+
+// buildVersion is used when no version is set through -ldflags. It returns
+// the module version, or the VCS revision stamped by go build.
+func buildVersion() string {
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	if bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		return bi.Main.Version
+	}
+
+	var revision, modified string
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+
+	return revision
+}
@@ -0,0 +1,43 @@
+package otel
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestNewResource(t *testing.T) {
+
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment=staging,tenant=acme")
+
+	cfg := newConfig([]Option{
+		WithServiceName("service-test"),
+		WithServiceVersion("1.2.3"),
+		WithDeploymentEnvironment("dev"),
+		WithResourceAttributes(attribute.String("team", "observability")),
+	})
+
+	res, err := newResource(context.Background(), cfg)
+	assert.Nil(t, err)
+
+	attrs := map[attribute.Key]string{}
+	for _, kv := range res.Attributes() {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+
+	hostname, _ := os.Hostname()
+
+	assert.Equal(t, "service-test", attrs["service.name"])
+	assert.Equal(t, "1.2.3", attrs["service.version"])
+	assert.Equal(t, "staging", attrs["deployment.environment"])
+	assert.Equal(t, "acme", attrs["tenant"])
+	assert.Equal(t, "observability", attrs["team"])
+	assert.Equal(t, hostname, attrs["host.name"])
+	assert.NotEmpty(t, attrs["os.type"])
+	assert.NotEmpty(t, attrs["process.pid"])
+	assert.Equal(t, "go", attrs["process.runtime.name"])
+	assert.Equal(t, "opentelemetry", attrs["telemetry.sdk.name"])
+}
@@ -0,0 +1,79 @@
+package otel
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	envTracesSampler    = "OTEL_TRACES_SAMPLER"
+	envTracesSamplerArg = "OTEL_TRACES_SAMPLER_ARG"
+)
+
+// samplerFromEnv falls back to parentbased_always_on when the variables are
+// missing or invalid, so service-b always follows service-a's decision.
+func samplerFromEnv() sdktrace.Sampler {
+
+	name, ok := os.LookupEnv(envTracesSampler)
+	if !ok || strings.TrimSpace(name) == "" {
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+
+	sampler, err := parseSampler(name, os.Getenv(envTracesSamplerArg))
+	if err != nil {
+		slog.Warn("[invalid sampler]", "error", err.Error())
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+
+	return sampler
+}
+
+func parseSampler(name, arg string) (sdktrace.Sampler, error) {
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		ratio, err := parseSamplerRatio(arg)
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	case "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		ratio, err := parseSamplerRatio(arg)
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	}
+
+	return nil, fmt.Errorf("unsupported sampler: %s", name)
+}
+
+func parseSamplerRatio(arg string) (float64, error) {
+
+	if strings.TrimSpace(arg) == "" {
+		return 1, nil
+	}
+
+	ratio, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sampler ratio %q: %w", arg, err)
+	}
+	if ratio < 0 || ratio > 1 {
+		return 0, fmt.Errorf("sampler ratio out of range [0..1]: %s", arg)
+	}
+
+	return ratio, nil
+}
@@ -0,0 +1,57 @@
+package otel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestParseSampler(t *testing.T) {
+
+	type samplerLote struct {
+		name        string
+		arg         string
+		description string
+		fail        bool
+	}
+
+	table := []samplerLote{
+		{"always_on", "", sdktrace.AlwaysSample().Description(), false},
+		{"always_off", "", sdktrace.NeverSample().Description(), false},
+		{"traceidratio", "0.25", sdktrace.TraceIDRatioBased(0.25).Description(), false},
+		{"traceidratio", "", sdktrace.TraceIDRatioBased(1).Description(), false},
+		{"parentbased_always_on", "", sdktrace.ParentBased(sdktrace.AlwaysSample()).Description(), false},
+		{"parentbased_always_off", "", sdktrace.ParentBased(sdktrace.NeverSample()).Description(), false},
+		{" ParentBased_TraceIdRatio ", "0.1", sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.1)).Description(), false},
+		{"parentbased_traceidratio", "1.5", "", true},
+		{"parentbased_traceidratio", "half", "", true},
+		{"jaeger_remote", "", "", true},
+	}
+	for _, item := range table {
+		sampler, err := parseSampler(item.name, item.arg)
+		if item.fail {
+			assert.Error(t, err)
+			assert.Nil(t, sampler)
+		} else {
+			assert.Nil(t, err)
+			assert.Equal(t, item.description, sampler.Description())
+		}
+	}
+}
+
+func TestSamplerFromEnv(t *testing.T) {
+
+	t.Setenv(envTracesSampler, "parentbased_traceidratio")
+	t.Setenv(envTracesSamplerArg, "0.5")
+	assert.Equal(t, sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.5)).Description(), samplerFromEnv().Description())
+
+	t.Setenv(envTracesSampler, "invalid")
+	assert.Equal(t, sdktrace.ParentBased(sdktrace.AlwaysSample()).Description(), samplerFromEnv().Description())
+
+	t.Setenv(envTracesSampler, "")
+	assert.Equal(t, sdktrace.ParentBased(sdktrace.AlwaysSample()).Description(), samplerFromEnv().Description())
+
+	cfg := newConfig([]Option{WithSamplingRatio(0.2)})
+	assert.Equal(t, sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.2)).Description(), cfg.sampler.Description())
+}
@@ -0,0 +1,108 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceHandler decorates a slog.Handler adding the trace_id and span_id
+// of the span found in the record context.
+type TraceHandler struct {
+	slog.Handler
+}
+
+func NewTraceHandler(h slog.Handler) *TraceHandler {
+	return &TraceHandler{Handler: h}
+}
+
+func (h *TraceHandler) Handle(ctx context.Context, r slog.Record) error {
+
+	sc := trace.SpanContextFromContext(ctx)
+	if sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *TraceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return NewTraceHandler(h.Handler.WithAttrs(attrs))
+}
+
+func (h *TraceHandler) WithGroup(name string) slog.Handler {
+	return NewTraceHandler(h.Handler.WithGroup(name))
+}
+
+// NewBridgeHandler returns a handler that writes every record to next and
+// also emits the ones at or above level through the global OpenTelemetry
+// LoggerProvider, which InitProvider exports to the collector. It is only
+// worth installing when LogsEnabled.
+func NewBridgeHandler(name string, next slog.Handler, level slog.Leveler) slog.Handler {
+	return &teeHandler{
+		handlers: []slog.Handler{next, &levelHandler{Handler: otelslog.NewHandler(name), level: level}},
+	}
+}
+
+// levelHandler drops the records below level before they reach Handler.
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+type teeHandler struct {
+	handlers []slog.Handler
+}
+
+func (h *teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, hd := range h.handlers {
+		if hd.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, hd := range h.handlers {
+		if hd.Enabled(ctx, r.Level) {
+			errs = append(errs, hd.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, hd := range h.handlers {
+		handlers[i] = hd.WithAttrs(attrs)
+	}
+	return &teeHandler{handlers: handlers}
+}
+
+func (h *teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, hd := range h.handlers {
+		handlers[i] = hd.WithGroup(name)
+	}
+	return &teeHandler{handlers: handlers}
+}
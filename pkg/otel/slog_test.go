@@ -0,0 +1,94 @@
+package otel_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	otelpkg "github.com/felipeksw/goexpert-fullcycle-cloud-run/pkg/otel"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestTraceHandler(t *testing.T) {
+
+	var buf bytes.Buffer
+	logger := slog.New(otelpkg.NewTraceHandler(slog.NewJSONHandler(&buf, nil)))
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "TestTraceHandler")
+	defer span.End()
+
+	logger.With("component", "test").InfoContext(ctx, "[with span]")
+
+	var rec map[string]any
+	err := json.Unmarshal(buf.Bytes(), &rec)
+	assert.Nil(t, err)
+	assert.Equal(t, span.SpanContext().TraceID().String(), rec["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), rec["span_id"])
+	assert.Equal(t, "test", rec["component"])
+
+	buf.Reset()
+	logger.InfoContext(context.Background(), "[without span]")
+
+	rec = map[string]any{}
+	err = json.Unmarshal(buf.Bytes(), &rec)
+	assert.Nil(t, err)
+	assert.NotContains(t, rec, "trace_id")
+	assert.NotContains(t, rec, "span_id")
+}
+
+type memoryLogExporter struct {
+	records []sdklog.Record
+}
+
+func (e *memoryLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	for _, r := range records {
+		e.records = append(e.records, r.Clone())
+	}
+	return nil
+}
+
+func (e *memoryLogExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func (e *memoryLogExporter) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+func TestBridgeHandler(t *testing.T) {
+
+	exp := &memoryLogExporter{}
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exp)))
+	defer lp.Shutdown(context.Background())
+	global.SetLoggerProvider(lp)
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "TestBridgeHandler")
+	defer span.End()
+
+	var buf bytes.Buffer
+	logger := slog.New(otelpkg.NewBridgeHandler("test", slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), slog.LevelInfo))
+
+	logger.InfoContext(ctx, "[bridged]", "cep", "01001000")
+
+	assert.Contains(t, buf.String(), "[bridged]")
+	assert.Len(t, exp.records, 1)
+	assert.Equal(t, "[bridged]", exp.records[0].Body().AsString())
+	assert.Equal(t, span.SpanContext().TraceID(), exp.records[0].TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), exp.records[0].SpanID())
+
+	// debug records stay local, the bridge has its own level
+	logger.With("service", "test").DebugContext(ctx, "[debug]")
+	assert.Contains(t, buf.String(), "[debug]")
+	assert.Len(t, exp.records, 1)
+}
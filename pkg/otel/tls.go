@@ -0,0 +1,92 @@
+package otel
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	envExporterCertificate       = "OTEL_EXPORTER_OTLP_CERTIFICATE"
+	envExporterClientCertificate = "OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE"
+	envExporterClientKey         = "OTEL_EXPORTER_OTLP_CLIENT_KEY"
+)
+
+// TLSConfig points to PEM files. CAFile replaces the system roots, and
+// CertFile and KeyFile enable mTLS when both are set.
+type TLSConfig struct {
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+func (t TLSConfig) build() (*tls.Config, error) {
+
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: t.ServerName,
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("failed to parse CA file: no certificate found")
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, errors.New("client certificate and key must be set together")
+		}
+
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+func (c *config) transportCredentials() (credentials.TransportCredentials, error) {
+
+	_, plaintext := c.target()
+	if plaintext {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsCfg, err := c.tls.build()
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+// headerCredentials adds headers resolved at every export call, so rotating
+// tokens do not need a new connection. They are only sent over TLS.
+type headerCredentials struct {
+	headers func(context.Context) (map[string]string, error)
+}
+
+func (h headerCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return h.headers(ctx)
+}
+
+func (h headerCredentials) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,258 @@
+package otel_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	otelpkg "github.com/felipeksw/goexpert-fullcycle-cloud-run/pkg/otel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+type pemFiles struct {
+	caFile         string
+	serverCertFile string
+	serverKeyFile  string
+	clientCertFile string
+	clientKeyFile  string
+}
+
+func writePem(t *testing.T, path, kind string, der []byte) {
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600)
+	require.Nil(t, err)
+}
+
+func newCert(t *testing.T, dir, name string, tmpl *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.Nil(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	writePem(t, filepath.Join(dir, name+".crt"), "CERTIFICATE", der)
+	writePem(t, filepath.Join(dir, name+".key"), "EC PRIVATE KEY", keyDer)
+
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+
+	return cert, key
+}
+
+func newPemFiles(t *testing.T) pemFiles {
+
+	dir := t.TempDir()
+	notAfter := time.Now().Add(time.Hour)
+
+	ca, caKey := newCert(t, dir, "ca", &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil, nil)
+
+	newCert(t, dir, "server", &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "otel-collector"},
+		DNSNames:     []string{"otel-collector"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+
+	newCert(t, dir, "client", &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "service-a"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	return pemFiles{
+		caFile:         filepath.Join(dir, "ca.crt"),
+		serverCertFile: filepath.Join(dir, "server.crt"),
+		serverKeyFile:  filepath.Join(dir, "server.key"),
+		clientCertFile: filepath.Join(dir, "client.crt"),
+		clientKeyFile:  filepath.Join(dir, "client.key"),
+	}
+}
+
+type fakeCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	mu            sync.Mutex
+	spans         int
+	authorization []string
+}
+
+func (c *fakeCollector) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.authorization = append(c.authorization, md.Get("authorization")...)
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans += len(ss.Spans)
+		}
+	}
+
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+type fakeMetricCollector struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+}
+
+func (fakeMetricCollector) Export(context.Context, *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+type fakeLogCollector struct {
+	collogspb.UnimplementedLogsServiceServer
+}
+
+func (fakeLogCollector) Export(context.Context, *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func startCollector(t *testing.T, files pemFiles, clientAuth tls.ClientAuthType) (string, *fakeCollector) {
+
+	cert, err := tls.LoadX509KeyPair(files.serverCertFile, files.serverKeyFile)
+	require.Nil(t, err)
+
+	caPem, err := os.ReadFile(files.caFile)
+	require.Nil(t, err)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caPem)
+
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   clientAuth,
+	})))
+
+	collector := &fakeCollector{}
+	coltracepb.RegisterTraceServiceServer(srv, collector)
+	colmetricpb.RegisterMetricsServiceServer(srv, fakeMetricCollector{})
+	collogspb.RegisterLogsServiceServer(srv, fakeLogCollector{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String(), collector
+}
+
+func exportSpan(t *testing.T, shutdownTimeout time.Duration, opts ...otelpkg.Option) {
+
+	shutdown, err := otelpkg.InitProvider(context.Background(), opts...)
+	require.Nil(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "exportSpan")
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	shutdown(ctx)
+}
+
+func TestInitProviderTLSWithBearerToken(t *testing.T) {
+
+	files := newPemFiles(t)
+	addr, collector := startCollector(t, files, tls.NoClientCert)
+
+	exportSpan(t, 5*time.Second,
+		otelpkg.WithServiceName("test"),
+		otelpkg.WithEndpoint("https://"+addr),
+		otelpkg.WithTLS(otelpkg.TLSConfig{CAFile: files.caFile, ServerName: "otel-collector"}),
+		otelpkg.WithBearerToken("secret"),
+	)
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	assert.Equal(t, 1, collector.spans)
+	assert.Contains(t, collector.authorization, "Bearer secret")
+}
+
+func TestInitProviderMutualTLS(t *testing.T) {
+
+	files := newPemFiles(t)
+	addr, collector := startCollector(t, files, tls.RequireAndVerifyClientCert)
+
+	exportSpan(t, 5*time.Second,
+		otelpkg.WithServiceName("test"),
+		otelpkg.WithEndpoint(addr),
+		otelpkg.WithTLS(otelpkg.TLSConfig{
+			CAFile:     files.caFile,
+			CertFile:   files.clientCertFile,
+			KeyFile:    files.clientKeyFile,
+			ServerName: "otel-collector",
+		}),
+	)
+
+	collector.mu.Lock()
+	assert.Equal(t, 1, collector.spans)
+	collector.mu.Unlock()
+
+	// without the client certificate the handshake is rejected
+	exportSpan(t, 500*time.Millisecond,
+		otelpkg.WithServiceName("test"),
+		otelpkg.WithEndpoint(addr),
+		otelpkg.WithTLS(otelpkg.TLSConfig{CAFile: files.caFile, ServerName: "otel-collector"}),
+	)
+
+	collector.mu.Lock()
+	assert.Equal(t, 1, collector.spans)
+	collector.mu.Unlock()
+}
+
+func TestInitProviderInvalidTLS(t *testing.T) {
+
+	files := newPemFiles(t)
+
+	_, err := otelpkg.InitProvider(context.Background(),
+		otelpkg.WithEndpoint("https://otel-collector:4317"),
+		otelpkg.WithTLS(otelpkg.TLSConfig{CertFile: files.clientCertFile}),
+	)
+	assert.ErrorContains(t, err, "client certificate and key must be set together")
+
+	_, err = otelpkg.InitProvider(context.Background(),
+		otelpkg.WithEndpoint("https://otel-collector:4317"),
+		otelpkg.WithTLS(otelpkg.TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.crt")}),
+	)
+	assert.ErrorContains(t, err, "failed to read CA file")
+}